@@ -2,11 +2,14 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -18,16 +21,169 @@ import (
 )
 
 const (
-	ConsoleOutput = "console"
-	JSONOutput    = "json"
-	GithubOutput  = "github"
+	ConsoleOutput    = "console"
+	JSONOutput       = "json"
+	GithubOutput     = "github"
+	JSONLinesOutput  = "jsonl"
+	SARIFOutput      = "sarif"
+	CheckstyleOutput = "checkstyle"
+	JUnitOutput      = "junit"
+	GitlabOutput     = "gitlab"
+
+	FileKey     = "file"
+	LineKey     = "line"
+	ColumnKey   = "column"
+	AnalyzerKey = "analyzer"
+	PackageKey  = "package"
+	SeverityKey = "severity"
+	MessageKey  = "message"
+
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+
+	// DefaultTabWidth is the default number of the spaces to render a tab in the console output
+	DefaultTabWidth = 4
+	// DefaultFixIterations is the default maximum number of the runs to apply the conflicted fixes
+	DefaultFixIterations = 3
 
 	ErrorLevel   = "error"
 	WarningLevel = "warning"
 	InfoLevel    = "info"
 )
 
-var oneOfOutputFormats = strings.Join([]string{ConsoleOutput, JSONOutput, GithubOutput}, ", ")
+var (
+	// DefaultSort is the default order of the printed issues
+	DefaultSort   = []string{FileKey, LineKey, ColumnKey, AnalyzerKey}
+	oneOfSortKeys = strings.Join([]string{FileKey, LineKey, ColumnKey, AnalyzerKey, PackageKey, SeverityKey, MessageKey}, ", ")
+	oneOfGroups   = strings.Join([]string{FileKey, AnalyzerKey, PackageKey}, ", ")
+)
+
+var (
+	outputFormats      = []string{ConsoleOutput, JSONOutput, GithubOutput, JSONLinesOutput, SARIFOutput, CheckstyleOutput, JUnitOutput, GitlabOutput}
+	oneOfOutputFormats = strings.Join(outputFormats, ", ")
+)
+
+// OutputFile is the output format to be written to the file or to stdout if the path is empty or `-`
+type OutputFile struct {
+	Format string `json:"format" yaml:"format"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+// IsStdout returns true if the output is written to stdout
+func (o *OutputFile) IsStdout() bool {
+	return o.Path == "" || o.Path == "-"
+}
+
+func (o *OutputFile) String() string {
+	if o.IsStdout() {
+		return o.Format
+	}
+	return o.Format + ":" + o.Path
+}
+
+// outputsFlag is the repeatable `-output format:path` flag
+type outputsFlag []*OutputFile
+
+func (f *outputsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*f))
+	for _, o := range *f {
+		parts = append(parts, o.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set splits the value after a known format, so the path may contain colons, e.g. `sarif:C:\out\x.sarif`
+func (f *outputsFlag) Set(value string) error {
+	for _, format := range outputFormats {
+		if len(value) > len(format) && value[len(format)] == ':' && strings.EqualFold(value[:len(format)], format) {
+			*f = append(*f, &OutputFile{Format: value[:len(format)], Path: value[len(format)+1:]})
+			return nil
+		}
+	}
+	format, path, _ := strings.Cut(value, ":")
+	*f = append(*f, &OutputFile{Format: format, Path: path})
+	return nil
+}
+
+// parseOutputs combines the outputs of the flags, the output and outputs options and validates them,
+// the flags override the config options and the console output is used by default
+func parseOutputs(config *Config, flags outputsFlag) error {
+	switch {
+	case len(flags) > 0:
+		config.Outputs = flags
+	case config.Output != "":
+		config.Outputs = append([]*OutputFile{{Format: config.Output}}, config.Outputs...)
+	case len(config.Outputs) == 0:
+		config.Outputs = []*OutputFile{{Format: ConsoleOutput}}
+	}
+	stdout := 0
+	for _, o := range config.Outputs {
+		o.Format = strings.ToLower(o.Format)
+		switch o.Format {
+		case "":
+			o.Format = ConsoleOutput
+		case ConsoleOutput, JSONOutput, GithubOutput, JSONLinesOutput, SARIFOutput, CheckstyleOutput, JUnitOutput, GitlabOutput:
+		default:
+			return fmt.Errorf("wrong output format %q, must be one of: %s", o.Format, oneOfOutputFormats)
+		}
+		if o.IsStdout() {
+			stdout++
+		}
+	}
+	if stdout > 1 {
+		return errors.New("only one output can be written to stdout")
+	}
+	return nil
+}
+
+// parseOrder validates the sort keys and the grouping of the issues, the default sort keys are used if not set
+func parseOrder(config *Config) error {
+	if len(config.Sort) == 0 {
+		config.Sort = append([]string(nil), DefaultSort...)
+	}
+	for i, key := range config.Sort {
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case FileKey, LineKey, ColumnKey, AnalyzerKey, PackageKey, SeverityKey, MessageKey:
+		default:
+			return fmt.Errorf("wrong sort key %q, must be one of: %s", key, oneOfSortKeys)
+		}
+		config.Sort[i] = key
+	}
+	config.Group = strings.ToLower(config.Group)
+	switch config.Group {
+	case "", FileKey, AnalyzerKey, PackageKey:
+	default:
+		return fmt.Errorf("wrong group %q, must be one of: %s", config.Group, oneOfGroups)
+	}
+	return nil
+}
+
+// parseConsole validates the options of the console output and sets the defaults
+func parseConsole(config *Config) error {
+	config.Color = strings.ToLower(config.Color)
+	switch config.Color {
+	case "":
+		config.Color = ColorAuto
+	case ColorAuto, ColorAlways, ColorNever:
+	default:
+		return fmt.Errorf("wrong color mode %q, must be one of: %s", config.Color, strings.Join([]string{ColorAuto, ColorAlways, ColorNever}, ", "))
+	}
+	if config.Context < 0 {
+		return fmt.Errorf("wrong number of context lines %d, must not be negative", config.Context)
+	}
+	switch {
+	case config.TabWidth == 0:
+		config.TabWidth = DefaultTabWidth
+	case config.TabWidth < 0:
+		return fmt.Errorf("wrong tab width %d, must be positive", config.TabWidth)
+	}
+	return nil
+}
 
 type Config struct {
 	Analyzers  map[string]map[string]string `json:"analyzers" yaml:"analyzers"`
@@ -37,24 +193,121 @@ type Config struct {
 	MemProfile string                       `json:"memprofile" yaml:"memprofile"`
 	Trace      string                       `json:"trace" yaml:"trace"`
 	Output     string                       `json:"output" yaml:"output"`
+	Outputs    []*OutputFile                `json:"outputs" yaml:"outputs"`
+	Sort       []string                     `json:"sort" yaml:"sort"`
+	Group      string                       `json:"group" yaml:"group"`
+	Summary    bool                         `json:"summary" yaml:"summary"`
+	Merge      bool                         `json:"merge" yaml:"merge"`
+	Color      string                       `json:"color" yaml:"color"`
+	Context    int                          `json:"context" yaml:"context"`
+	TabWidth   int                          `json:"tab_width" yaml:"tab_width"`
 	GoVersion  string                       `json:"go_version" yaml:"go_version"`
+	CacheDir   string                       `json:"cache_dir" yaml:"cache_dir"`
+	Baseline   string                       `json:"baseline" yaml:"baseline"`
 	Args       []string                     `json:"-" yaml:"-"`
+	Patterns   []string                     `json:"-" yaml:"-"`
 	Severity   []*SeverityRule              `json:"severity" yaml:"severity"`
 	Exclude    []*Rule                      `json:"exclude" yaml:"exclude"`
+	Nolint     Nolint                       `json:"nolint" yaml:"nolint"`
 	Test       bool                         `json:"test" yaml:"test"`
-	Fix        bool                         `json:"fix" yaml:"fix"`
+	// Fix is true if the fixes are enabled by the flag or by the config
+	Fix       bool      `json:"-" yaml:"-"`
+	FixConfig FixConfig `json:"fix" yaml:"fix"`
+	// FixIterations is the maximum number of the runs to apply the fixes conflicted with other fixes
+	FixIterations int `json:"fix_iterations" yaml:"fix_iterations"`
+	// FixAlternatives selects one of the alternative fixes of an issue, the first one is used by default
+	FixAlternatives []*FixAlternative `json:"fix_alternatives" yaml:"fix_alternatives"`
+	// Interactive asks for each fix whether to apply it, it can be set by the flag only
+	Interactive bool `json:"-" yaml:"-"`
+	// DryRun prints the patch of the fixes to stdout instead of applying them and the outputs to stdout go to stderr,
+	// it can be set by the flag only
+	DryRun bool `json:"-" yaml:"-"`
+	// Patch is the path to write the patch of the fixes to instead of applying them, it can be set by the flag only
+	Patch   string `json:"-" yaml:"-"`
+	NoCache bool   `json:"no_cache" yaml:"no_cache"`
 }
 
 type Rule struct {
-	PackageRE *regexp.Regexp `json:"-" yaml:"-"`
-	PathRE    *regexp.Regexp `json:"-" yaml:"-"`
+	PackageRE      *regexp.Regexp `json:"-" yaml:"-"`
+	PathRE         *regexp.Regexp `json:"-" yaml:"-"`
+	MessageRE      *regexp.Regexp `json:"-" yaml:"-"`
+	Package        string         `json:"package" yaml:"package"`
+	Analyzer       string         `json:"analyzer" yaml:"analyzer"`
+	Path           string         `json:"path" yaml:"path"`
+	Message        string         `json:"message" yaml:"message"`
+	Severity       string         `json:"severity" yaml:"severity"`
+	Fingerprint    string         `json:"fingerprint" yaml:"fingerprint"`
+	GitRef         string         `json:"git_ref" yaml:"git_ref"`
+	Patch          string         `json:"patch" yaml:"patch"`
+	GitLines       bool           `json:"git_lines" yaml:"git_lines"`
+	GitUncommitted bool           `json:"git_uncommitted" yaml:"git_uncommitted"`
+}
+
+// FixConfig enables the fixes and restricts them to the analyzers, the severity levels and the paths of the changed files,
+// the empty list allows all, `fix: true` is a short form to enable all fixes
+type FixConfig struct {
+	PathsRE   []*regexp.Regexp `json:"-" yaml:"-"`
+	Enabled   bool             `json:"enabled" yaml:"enabled"`
+	Analyzers []string         `json:"analyzers" yaml:"analyzers"`
+	Severity  []string         `json:"severity" yaml:"severity"`
+	Paths     []string         `json:"paths" yaml:"paths"`
+}
+
+// UnmarshalYAML decodes either a boolean or a section
+func (f *FixConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&f.Enabled)
+	}
+	type plain FixConfig
+	return value.Decode((*plain)(f))
+}
+
+func compileFixConfig(fix *FixConfig) error {
+	for i, level := range fix.Severity {
+		fix.Severity[i] = strings.ToLower(level)
+		switch fix.Severity[i] {
+		case ErrorLevel, WarningLevel, InfoLevel:
+		default:
+			return fmt.Errorf("wrong severity level %q, must be one of: %s", level, strings.Join([]string{ErrorLevel, WarningLevel, InfoLevel}, ", "))
+		}
+	}
+	fix.PathsRE = fix.PathsRE[:0]
+	for _, path := range fix.Paths {
+		re, err := regexp.Compile(path)
+		if err != nil {
+			return err
+		}
+		fix.PathsRE = append(fix.PathsRE, re)
+	}
+	return nil
+}
+
+// FixAlternative selects the suggested fix by the message when an analyzer suggests several alternative fixes,
+// the rule without the analyzer is applied to all analyzers and the rule without the message is ignored
+type FixAlternative struct {
 	MessageRE *regexp.Regexp `json:"-" yaml:"-"`
-	Package   string         `json:"package" yaml:"package"`
 	Analyzer  string         `json:"analyzer" yaml:"analyzer"`
-	Path      string         `json:"path" yaml:"path"`
 	Message   string         `json:"message" yaml:"message"`
-	Severity  string         `json:"severity" yaml:"severity"`
-	GitRef    string         `json:"git_ref" yaml:"git_ref"`
+}
+
+func compileFixAlternatives(alternatives []*FixAlternative) error {
+	for _, alt := range alternatives {
+		if alt.Message == "" {
+			continue
+		}
+		var err error
+		alt.MessageRE, err = regexp.Compile(alt.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Nolint struct {
+	Allowed       []string `json:"allowed" yaml:"allowed"`
+	RequireReason bool     `json:"require_reason" yaml:"require_reason"`
+	Check         bool     `json:"check" yaml:"check"`
 }
 
 type SeverityRule struct {
@@ -83,6 +336,12 @@ func compileRules(exclude []*Rule) error {
 				return err
 			}
 		}
+		if rule.GitLines && rule.GitRef == "" && rule.Patch == "" {
+			return errors.New("git_lines requires git_ref or patch")
+		}
+		if rule.GitUncommitted && rule.GitRef == "" {
+			return errors.New("git_uncommitted requires git_ref")
+		}
 		rule.Severity = strings.ToLower(rule.Severity)
 		switch rule.Severity {
 		case "":
@@ -97,13 +356,14 @@ func compileRules(exclude []*Rule) error {
 func ParseConfig() *Config {
 	var (
 		configPath string
+		outputs    outputsFlag
 		config     = Config{Analyzers: map[string]map[string]string{}}
 	)
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	fs.SetOutput(&bytes.Buffer{}) // mute any prints
 	for _, set := range []*flag.FlagSet{flag.CommandLine, fs} {
 		set.StringVar(&configPath, "config", "", "A path to a config file in json or yaml format.")
-		set.StringVar(&config.Output, "output", "", "Output format, one of: "+oneOfOutputFormats)
+		set.Var(&outputs, "output", "Output format and optional file path as `format[:path]`, can be repeated, format is one of: "+oneOfOutputFormats)
 	}
 	// default multichecker flags
 	fs.StringVar(&config.Debug, "debug", "", "")
@@ -112,8 +372,25 @@ func ParseConfig() *Config {
 	fs.StringVar(&config.Trace, "trace", "", "")
 	fs.BoolVar(&config.Test, "test", true, "")
 	fs.BoolVar(&config.Fix, "fix", false, "")
+	fs.BoolVar(&config.Interactive, "interactive", false, "")
+	fs.IntVar(&config.FixIterations, "fix-iterations", 0, "")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "")
+	fs.StringVar(&config.Patch, "patch", "", "")
 	var jsonFlag bool
 	fs.BoolVar(&jsonFlag, "json", false, "")
+	// cache flags
+	fs.StringVar(&config.CacheDir, "cache-dir", "", "")
+	fs.BoolVar(&config.NoCache, "no-cache", false, "")
+	fs.StringVar(&config.Baseline, "baseline", "", "")
+	// output flags
+	var sortFlag string
+	fs.StringVar(&sortFlag, "sort", "", "")
+	fs.StringVar(&config.Group, "group", "", "")
+	fs.BoolVar(&config.Summary, "summary", false, "")
+	fs.BoolVar(&config.Merge, "merge", false, "")
+	fs.StringVar(&config.Color, "color", "", "")
+	fs.IntVar(&config.Context, "context", 0, "")
+	fs.IntVar(&config.TabWidth, "tab-width", 0, "")
 	// analyzer's flags
 	for _, analyzer := range analyzers.Analyzers {
 		fs.Bool(analyzer.Name, false, "")
@@ -138,6 +415,15 @@ func ParseConfig() *Config {
 		if err := compileRules(config.Exclude); err != nil {
 			log.Fatal(err)
 		}
+		if err := compileFixAlternatives(config.FixAlternatives); err != nil {
+			log.Fatal(err)
+		}
+		if err := compileFixConfig(&config.FixConfig); err != nil {
+			log.Fatal(err)
+		}
+		if config.FixConfig.Enabled {
+			config.Fix = true
+		}
 		for _, sev := range config.Severity {
 			sev.Level = strings.ToLower(sev.Level)
 			switch sev.Level {
@@ -152,23 +438,38 @@ func ParseConfig() *Config {
 			}
 		}
 	}
+	if config.DryRun || config.Patch != "" {
+		config.Fix = true
+	}
+	switch {
+	case config.FixIterations == 0:
+		config.FixIterations = DefaultFixIterations
+	case config.FixIterations < 0:
+		log.Fatalf("wrong number of fix iterations %d, must be positive", config.FixIterations)
+	}
 	if jsonFlag {
-		config.Output = "json"
+		outputs = append(outputs, &OutputFile{Format: JSONOutput})
 	}
-	config.Output = strings.ToLower(config.Output)
-	switch config.Output {
-	case "":
-		config.Output = ConsoleOutput
-	case ConsoleOutput, JSONOutput, GithubOutput:
-	default:
-		log.Fatal("output must be one of: " + oneOfOutputFormats)
+	if err := parseOutputs(&config, outputs); err != nil {
+		log.Fatal(err)
+	}
+	if sortFlag != "" {
+		config.Sort = strings.Split(sortFlag, ",")
+	}
+	if err := parseOrder(&config); err != nil {
+		log.Fatal(err)
+	}
+	if err := parseConsole(&config); err != nil {
+		log.Fatal(err)
 	}
 
 	fs.Visit(func(f *flag.Flag) {
 		switch f.Name {
 		case "config", "output":
 			return
-		case "debug", "cpuprofile", "memprofile", "trace", "test", "fix", "json":
+		case "debug", "cpuprofile", "memprofile", "trace", "test", "fix", "interactive", "fix-iterations", "dry-run", "patch", "json":
+			return
+		case "cache-dir", "no-cache", "baseline", "sort", "group", "summary", "merge", "color", "context", "tab-width":
 			return
 		}
 		parts := strings.SplitN(f.Name, ".", 2)
@@ -221,13 +522,23 @@ func ParseConfig() *Config {
 		}
 	}
 
+	if !config.NoCache && config.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			log.Printf("getting user cache dir failed, the cache is disabled: %+v", err)
+			config.NoCache = true
+		} else {
+			config.CacheDir = filepath.Join(dir, "gochecker")
+		}
+	}
+
 	if err := ApplyModInfo(&config); err != nil {
 		log.Fatal("Reading info about go.mo failed: #+v", err)
 	}
 
 	// preparing the args
 
-	args := []string{"-json"}
+	var args []string
 	if config.Test {
 		args = append(args, "-test")
 	}
@@ -246,10 +557,22 @@ func ParseConfig() *Config {
 	if config.Trace != "" {
 		args = append(args, "-trace", config.Trace)
 	}
-	for name, flags := range config.Analyzers {
+	// sort the names to get the same args for the same config
+	names := make([]string, 0, len(config.Analyzers))
+	for name := range config.Analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flags := config.Analyzers[name]
 		args = append(args, "-"+name)
-		for fname, value := range flags {
-			if value != "" {
+		fnames := make([]string, 0, len(flags))
+		for fname := range flags {
+			fnames = append(fnames, fname)
+		}
+		sort.Strings(fnames)
+		for _, fname := range fnames {
+			if value := flags[fname]; value != "" {
 				switch strings.ToLower(value) {
 				case "false":
 					continue
@@ -261,7 +584,8 @@ func ParseConfig() *Config {
 			}
 		}
 	}
-	config.Args = append(args, fs.Args()...)
+	config.Args = args
+	config.Patterns = fs.Args()
 	return &config
 }
 
@@ -283,6 +607,18 @@ func GenerateConfig() {
 			Message:  "",
 		},
 	}
+	config.FixAlternatives = []*FixAlternative{
+		{
+			Analyzer: "",
+			Message:  "",
+		},
+	}
+	config.Outputs = []*OutputFile{
+		{
+			Format: "",
+			Path:   "",
+		},
+	}
 	config.Severity = []*SeverityRule{
 		{
 			Level: "error",
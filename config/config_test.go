@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+func TestParseOutputs(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		values   []string
+		conf     config.Config
+		expected []*config.OutputFile
+		err      string
+	}{
+		{
+			name:     "default",
+			expected: []*config.OutputFile{{Format: config.ConsoleOutput}},
+		},
+		{
+			name:     "windows path",
+			values:   []string{`sarif:C:\out.sarif`},
+			expected: []*config.OutputFile{{Format: config.SARIFOutput, Path: `C:\out.sarif`}},
+		},
+		{
+			name:     "empty path",
+			values:   []string{"json:"},
+			expected: []*config.OutputFile{{Format: config.JSONOutput}},
+		},
+		{
+			name:   "two outputs to stdout",
+			values: []string{"console", "JUnit:junit.xml", "gitlab:-"},
+			expected: []*config.OutputFile{
+				{Format: config.ConsoleOutput},
+				{Format: config.JUnitOutput, Path: "junit.xml"},
+				{Format: config.GitlabOutput, Path: "-"},
+			},
+			err: "only one output can be written to stdout",
+		},
+		{
+			name:     "flags override options",
+			values:   []string{"checkstyle:out.xml"},
+			conf:     config.Config{Output: config.GithubOutput},
+			expected: []*config.OutputFile{{Format: config.CheckstyleOutput, Path: "out.xml"}},
+		},
+		{
+			name: "output option first",
+			conf: config.Config{
+				Output:  config.GithubOutput,
+				Outputs: []*config.OutputFile{{Format: config.SARIFOutput, Path: "out.sarif"}},
+			},
+			expected: []*config.OutputFile{
+				{Format: config.GithubOutput},
+				{Format: config.SARIFOutput, Path: "out.sarif"},
+			},
+		},
+		{
+			name:     "unknown format",
+			values:   []string{"html:out.html"},
+			expected: []*config.OutputFile{{Format: "html", Path: "out.html"}},
+			err:      `wrong output format "html", must be one of: console, json, github, jsonl, sarif, checkstyle, junit, gitlab`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ParseOutputs(&tt.conf, tt.values...)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expected, tt.conf.Outputs)
+		})
+	}
+}
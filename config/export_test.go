@@ -0,0 +1,12 @@
+package config
+
+// ParseOutputs sets the values of the repeated `-output` flag and combines them with the outputs of the config
+func ParseOutputs(config *Config, values ...string) error {
+	var flags outputsFlag
+	for _, value := range values {
+		if err := flags.Set(value); err != nil {
+			return err
+		}
+	}
+	return parseOutputs(config, flags)
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	gci "github.com/daixiang0/gci/pkg/analyzer"
@@ -12,33 +14,62 @@ import (
 	"github.com/sv-tools/gochecker/analyzers/gofumpt"
 )
 
-// ModInfo contains the version of go and module name
+// ModInfo contains the version of go, the module name and its directory
 type ModInfo struct {
 	Path      string `json:"Path"`
+	Dir       string `json:"Dir"`
 	GoVersion string `json:"GoVersion"`
 }
 
-// GetModInfo returns the base info about module name and go version
-// TODO: support path in case of monorepo
-func GetModInfo() (*ModInfo, error) {
+// GetModInfo returns the main module with the given path, if the path is empty or is not a main module
+// the module containing the working directory is returned, a go workspace may have several main modules
+func GetModInfo(path string) (*ModInfo, error) {
 	cmd := exec.Command("go", "list", "-m", "-json")
 	cmd.Env = os.Environ()
-	data, err := cmd.CombinedOutput()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("go mod failed '%w' for data: %s", err, string(data))
+		return nil, fmt.Errorf("go mod failed '%w' for data: %s", err, stderr.String())
 	}
-	var mod ModInfo
-	if err := json.Unmarshal(data, &mod); err != nil {
-		return nil, fmt.Errorf("unmarshal data failed '%w' for data: %s", err, string(data))
+	var mods []*ModInfo
+	for d := json.NewDecoder(bytes.NewReader(data)); d.More(); {
+		var mod ModInfo
+		if err := d.Decode(&mod); err != nil {
+			return nil, fmt.Errorf("unmarshal data failed '%w' for data: %s", err, string(data))
+		}
+		if path != "" && mod.Path == path {
+			return &mod, nil
+		}
+		mods = append(mods, &mod)
+	}
+	if len(mods) == 0 {
+		return nil, fmt.Errorf("no main module found for data: %s", string(data))
 	}
 
-	return &mod, nil
+	// the innermost module containing the working directory
+	var found *ModInfo
+	if wd, err := os.Getwd(); err == nil {
+		for _, mod := range mods {
+			rel, err := filepath.Rel(mod.Dir, wd)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			if found == nil || len(mod.Dir) > len(found.Dir) {
+				found = mod
+			}
+		}
+	}
+	if found == nil {
+		found = mods[0]
+	}
+	return found, nil
 }
 
 // ApplyModInfo add the the mod info to all analyzers are in need of such info
 func ApplyModInfo(conf *Config) error {
 	if conf.Module == "" || conf.GoVersion == "" {
-		mod, err := GetModInfo()
+		mod, err := GetModInfo(conf.Module)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Baseline holds the known issues, which are not reported
+//
+// JSON example:
+// ```json
+//
+//	{
+//	  "issues": [
+//	    {
+//	      "fingerprint": "<hash>",
+//	      "package": "<package>",
+//	      "analyzer": "<analyzer>",
+//	      "path": "<path/to/file.go>",
+//	      "message": "<message>",
+//	      "count": 1
+//	    }
+//	  ]
+//	}
+//
+// ```
+type Baseline struct {
+	index  map[string]*BaselineIssue
+	Issues []*BaselineIssue `json:"issues"`
+}
+
+type BaselineIssue struct {
+	Fingerprint string `json:"fingerprint"`
+	Package     string `json:"package"`
+	Analyzer    string `json:"analyzer"`
+	Path        string `json:"path"`
+	Message     string `json:"message"`
+	Count       int    `json:"count"`
+	matched     int
+}
+
+func NewBaseline() *Baseline {
+	return &Baseline{index: make(map[string]*BaselineIssue)}
+}
+
+// LoadBaseline reads the baseline file
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	b := NewBaseline()
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("decoding baseline file %q failed: %w", filename, err)
+	}
+	for _, issue := range b.Issues {
+		b.index[baselineKey(issue.Package, issue.Fingerprint)] = issue
+	}
+	return b, nil
+}
+
+// Save writes the baseline file with the issues sorted by path, analyzer and message
+func (b *Baseline) Save(filename string) error {
+	sort.Slice(b.Issues, func(i, j int) bool {
+		x, y := b.Issues[i], b.Issues[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Analyzer != y.Analyzer {
+			return x.Analyzer < y.Analyzer
+		}
+		if x.Message != y.Message {
+			return x.Message < y.Message
+		}
+		if x.Package != y.Package {
+			return x.Package < y.Package
+		}
+		return x.Fingerprint < y.Fingerprint
+	})
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// Add records the issue in the baseline
+func (b *Baseline) Add(pkg, analyzer string, issue *Issue) {
+	fingerprint := issueFingerprint(pkg, analyzer, issue)
+	key := baselineKey(pkg, fingerprint)
+	if v, ok := b.index[key]; ok {
+		v.Count++
+		return
+	}
+	v := &BaselineIssue{
+		Fingerprint: fingerprint,
+		Package:     pkg,
+		Analyzer:    analyzer,
+		Path:        issuePath(issue.PosN),
+		Message:     issue.Message,
+		Count:       1,
+	}
+	b.index[key] = v
+	b.Issues = append(b.Issues, v)
+}
+
+// Match returns true if the issue is known, each entry of the baseline matches up to its count of issues
+func (b *Baseline) Match(pkg, analyzer string, issue *Issue) bool {
+	v, ok := b.index[baselineKey(pkg, issueFingerprint(pkg, analyzer, issue))]
+	if !ok || v.matched >= v.Count {
+		return false
+	}
+	v.matched++
+	return true
+}
+
+// Stale returns the entries of the given packages, which did not match any issue
+func (b *Baseline) Stale(packages map[string]struct{}) []*BaselineIssue {
+	var stale []*BaselineIssue
+	for _, issue := range b.Issues {
+		if _, ok := packages[issue.Package]; ok && issue.matched < issue.Count {
+			stale = append(stale, issue)
+		}
+	}
+	return stale
+}
+
+func baselineKey(pkg, fingerprint string) string {
+	return pkg + "\x00" + fingerprint
+}
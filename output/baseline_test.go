@@ -0,0 +1,83 @@
+package output_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestBaseline(t *testing.T) {
+	issue := func(fingerprint string) *output.Issue {
+		return &output.Issue{PosN: "a.go:1:1", Message: "issue " + fingerprint, Fingerprint: fingerprint}
+	}
+	for _, tt := range []struct {
+		name     string
+		known    []string
+		reported []string
+		matched  []bool
+		stale    map[string]int
+	}{
+		{
+			name:     "all matched",
+			known:    []string{"a", "b"},
+			reported: []string{"b", "a"},
+			matched:  []bool{true, true},
+		},
+		{
+			name:     "new issue",
+			known:    []string{"a"},
+			reported: []string{"a", "b"},
+			matched:  []bool{true, false},
+		},
+		{
+			name:     "count above the baseline",
+			known:    []string{"a", "a"},
+			reported: []string{"a", "a", "a"},
+			matched:  []bool{true, true, false},
+		},
+		{
+			name:     "count below the baseline",
+			known:    []string{"a", "a", "b"},
+			reported: []string{"a", "b"},
+			matched:  []bool{true, true},
+			stale:    map[string]int{"a": 2},
+		},
+		{
+			name:  "fixed issues",
+			known: []string{"a", "b"},
+			stale: map[string]int{"a": 1, "b": 1},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := output.NewBaseline()
+			for _, fingerprint := range tt.known {
+				b.Add("pkg", "shadow", issue(fingerprint))
+			}
+			// the counted entries survive the round trip
+			filename := filepath.Join(t.TempDir(), "baseline.json")
+			require.NoError(t, b.Save(filename))
+			b, err := output.LoadBaseline(filename)
+			require.NoError(t, err)
+
+			var matched []bool
+			for _, fingerprint := range tt.reported {
+				matched = append(matched, b.Match("pkg", "shadow", issue(fingerprint)))
+			}
+			require.Equal(t, tt.matched, matched)
+			require.False(t, b.Match("other", "shadow", issue("a")), "other package")
+
+			stale := make(map[string]int)
+			for _, entry := range b.Stale(map[string]struct{}{"pkg": {}}) {
+				stale[entry.Fingerprint] = entry.Count
+			}
+			if tt.stale == nil {
+				tt.stale = map[string]int{}
+			}
+			require.Equal(t, tt.stale, stale)
+			require.Empty(t, b.Stale(map[string]struct{}{"other": {}}), "not analyzed package")
+		})
+	}
+}
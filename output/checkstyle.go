@@ -0,0 +1,106 @@
+package output
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+type (
+	checkstyleOutput struct {
+		XMLName xml.Name          `xml:"checkstyle"`
+		Version string            `xml:"version,attr"`
+		Files   []*checkstyleFile `xml:"file"`
+	}
+	checkstyleFile struct {
+		Name   string             `xml:"name,attr"`
+		Errors []*checkstyleError `xml:"error"`
+	}
+	checkstyleError struct {
+		Line     int    `xml:"line,attr"`
+		Column   int    `xml:"column,attr,omitempty"`
+		Severity string `xml:"severity,attr"`
+		Message  string `xml:"message,attr"`
+		Source   string `xml:"source,attr"`
+	}
+)
+
+// PrintAsCheckstyle prints all issues as a checkstyle xml document grouped by files,
+// the errors of the analyzers are reported for the file named as the package
+func PrintAsCheckstyle(w io.Writer, diag *Diagnostic) {
+	files := make(map[string]*checkstyleFile)
+	getCheckstyleFile := func(name string) *checkstyleFile {
+		f, ok := files[name]
+		if !ok {
+			f = &checkstyleFile{Name: name}
+			files[name] = f
+		}
+		return f
+	}
+	for pkgName, pkg := range *diag {
+		for name, obj := range pkg {
+			if obj.Error != "" {
+				f := getCheckstyleFile(pkgName)
+				f.Errors = append(f.Errors, &checkstyleError{
+					Severity: "error",
+					Message:  obj.Error,
+					Source:   name,
+				})
+				continue
+			}
+			for _, issue := range obj.Issues {
+				filename, line, pos := parsePosN(issue.PosN)
+				if cf, err := getFile(filename); err == nil {
+					filename = cf.Filename
+				}
+				message := issue.Message
+				if issue.Category != "" {
+					message = issue.Category + ": " + message
+				}
+				for _, text := range issueDetails(issue) {
+					message += "\n" + text
+				}
+				e := checkstyleError{
+					Severity: issue.SeverityLevel,
+					Message:  message,
+					Source:   name,
+				}
+				if line > 0 {
+					e.Line = line
+				}
+				if pos > 0 {
+					e.Column = pos
+				}
+				f := getCheckstyleFile(filename)
+				f.Errors = append(f.Errors, &e)
+			}
+		}
+	}
+
+	out := checkstyleOutput{Version: "5.0"}
+	for _, name := range sortedKeys(files) {
+		f := files[name]
+		sort.SliceStable(f.Errors, func(i, j int) bool {
+			x, y := f.Errors[i], f.Errors[j]
+			if x.Line != y.Line {
+				return x.Line < y.Line
+			}
+			if x.Column != y.Column {
+				return x.Column < y.Column
+			}
+			return x.Source < y.Source
+		})
+		out.Files = append(out.Files, f)
+	}
+
+	data, err := xml.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		log.Fatalf("checkstyle ouput failed: %+v", err)
+	}
+	if _, err := io.WriteString(w, xml.Header+string(data)+"\n"); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+}
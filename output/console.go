@@ -9,8 +9,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/text/width"
 
 	"github.com/sv-tools/gochecker/config"
 )
@@ -23,6 +25,58 @@ type CachedFile struct {
 
 var cache = sync.Map{}
 
+// ResetFileCache forgets the content of the files read so far, e.g. after the fixes are written
+func ResetFileCache() {
+	for _, m := range []*sync.Map{&cache, &parsedFiles} {
+		m.Range(func(key, _ any) bool {
+			m.Delete(key)
+			return true
+		})
+	}
+}
+
+// issuePath returns the slash separated path of the file of the issue relative to the working directory
+func issuePath(posN string) string {
+	filename, _, _ := parsePosN(posN)
+	f, err := getFile(filename)
+	if err != nil {
+		log.Printf("reading file %q failed: %+v", filename, err)
+		return filepath.ToSlash(filename)
+	}
+	return filepath.ToSlash(f.Filename)
+}
+
+// repoRoot returns the root of the git repository or the working directory outside of a repository
+var repoRoot = sync.OnceValue(func() string {
+	root, err := gitRoot()
+	if err != nil {
+		if root, err = os.Getwd(); err != nil {
+			log.Fatalf("getting working directory failed: %+v", err)
+		}
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	return root
+})
+
+// repoPath returns the slash separated path of the file relative to the root of the repository,
+// false if the file is outside of the repository
+func repoPath(filename string) (string, bool) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", false
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	rel, err := filepath.Rel(repoRoot(), abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
 func getFile(filename string) (*CachedFile, error) {
 	obj, ok := cache.Load(filename)
 	if !ok {
@@ -57,186 +111,363 @@ const (
 	colorPurple = "\033[35m"
 )
 
-// PrintAsConsole prinst the issues to console with colors and returns false in case only warning or info isues
-func PrintAsConsole(diag *Diagnostic) (ret bool) {
+// consoleStyle holds the colors, empty if disabled, and the options to render the source code
+type consoleStyle struct {
+	reset, red, yellow, green, purple string
+	context                           int
+	tabWidth                          int
+}
+
+func newConsoleStyle(conf *config.Config, color bool) *consoleStyle {
+	st := consoleStyle{context: conf.Context, tabWidth: conf.TabWidth}
+	if st.tabWidth <= 0 {
+		st.tabWidth = config.DefaultTabWidth
+	}
+	if color {
+		st.reset, st.red, st.yellow, st.green, st.purple = colorReset, colorRed, colorYellow, colorGreen, colorPurple
+	}
+	return &st
+}
+
+// useColor returns true if the colors are enabled by the mode, in auto mode the colors are used
+// for a terminal only and can be disabled by the `NO_COLOR` environment variable
+func useColor(mode string, w io.Writer) bool {
+	switch mode {
+	case config.ColorAlways:
+		return true
+	case config.ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// PrintAsConsole prinst the issues to console with colors and returns false in case only warning or info isues.
+// The issues are sorted and grouped according to the config.
+func PrintAsConsole(w io.Writer, conf *config.Config, diag *Diagnostic) bool {
+	st := newConsoleStyle(conf, useColor(conf.Color, w))
+	return printEntriesAsConsole(w, SortIssues(diag, conf.Sort, conf.Group), conf.Group, st)
+}
+
+// printEntriesAsConsole renders the entries concurrently and prints them in order with a header for each group
+func printEntriesAsConsole(w io.Writer, entries []*Entry, group string, st *consoleStyle) (ret bool) {
+	bufs := make([]bytes.Buffer, len(entries))
 	wg := sync.WaitGroup{}
-	for _, pkg := range *diag {
-		for name, obj := range pkg {
-			if obj.Error != "" {
-				buf := bytes.Buffer{}
-				buf.WriteString(name)
-				buf.WriteString(": ")
-				buf.WriteString(colorRed)
-				buf.WriteString(obj.Error)
-				buf.WriteString(colorReset)
-				buf.WriteRune('\n')
-				if _, err := buf.WriteTo(os.Stdout); err != nil {
-					log.Printf("writing to stdout failed: %+v", err)
-					os.Exit(1)
+	for i, e := range entries {
+		if e.Error != "" {
+			bufs[i].WriteString(e.Analyzer)
+			bufs[i].WriteString(": ")
+			bufs[i].WriteString(st.red)
+			bufs[i].WriteString(e.Error)
+			bufs[i].WriteString(st.reset)
+			bufs[i].WriteRune('\n')
+			continue
+		}
+		if e.Issue.SeverityLevel == config.ErrorLevel {
+			ret = true
+		}
+		wg.Add(1)
+		go func(buf *bytes.Buffer, e *Entry) {
+			defer wg.Done()
+			writeConsoleIssue(buf, e.Analyzer, e.Issue, st)
+		}(&bufs[i], e)
+	}
+	wg.Wait()
+
+	var key string
+	for i, e := range entries {
+		if group != "" && (i == 0 || e.Key(group) != key) {
+			key = e.Key(group)
+			header := key
+			if group == config.FileKey {
+				if f, err := getFile(key); err == nil {
+					header = f.Filename
 				}
-				continue
 			}
-			for _, issue := range obj.Issues {
-				if issue.SeverityLevel == config.ErrorLevel {
-					ret = true
-				}
-				wg.Add(1)
-				name := name
-				issue := issue
-				go func() {
-					defer wg.Done()
+			if _, err := io.WriteString(w, st.purple+"==> "+header+st.reset+"\n"); err != nil {
+				log.Printf("writing output failed: %+v", err)
+				os.Exit(1)
+			}
+		}
+		if _, err := bufs[i].WriteTo(w); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+	}
+	return
+}
 
-					filename, line, pos := parsePosN(issue.PosN)
-					f, err := getFile(filename)
-					if err != nil {
-						log.Printf("reading file %q failed: %+v", filename, err)
-						return
-					}
+// writeConsoleIssue renders the issue with the source line and the suggested fixes
+func writeConsoleIssue(buf *bytes.Buffer, name string, issue *Issue, st *consoleStyle) {
+	filename, line, pos := parsePosN(issue.PosN)
+	f, err := getFile(filename)
+	if err != nil {
+		log.Printf("reading file %q failed: %+v", filename, err)
+		return
+	}
 
-					buf := bytes.Buffer{}
-					buf.WriteString(f.Filename)
-					if line != -1 {
-						buf.WriteString(":" + strconv.Itoa(line))
-						if pos != -1 {
-							buf.WriteString(":" + strconv.Itoa(pos))
-						}
-					}
-					switch issue.SeverityLevel {
-					case config.ErrorLevel:
-						buf.WriteString(colorRed)
-						buf.WriteString(": ERR")
-					case config.WarningLevel:
-						buf.WriteString(colorYellow)
-						buf.WriteString(": WRN")
-					case config.InfoLevel:
-						buf.WriteString(colorGreen)
-						buf.WriteString(": INF")
-					}
-					if issue.Category != "" {
-						buf.WriteString(": ")
-						buf.WriteString(issue.Category)
-					}
-					if issue.Message != "" {
-						buf.WriteString(": ")
-						buf.WriteString(issue.Message)
-					}
-					buf.WriteString(colorReset)
-					buf.WriteString(" (")
-					buf.WriteString(name)
-					buf.WriteRune(')')
+	buf.WriteString(f.Filename)
+	if line != -1 {
+		buf.WriteString(":" + strconv.Itoa(line))
+		if pos != -1 {
+			buf.WriteString(":" + strconv.Itoa(pos))
+		}
+	}
+	switch issue.SeverityLevel {
+	case config.ErrorLevel:
+		buf.WriteString(st.red)
+		buf.WriteString(": ERR")
+	case config.WarningLevel:
+		buf.WriteString(st.yellow)
+		buf.WriteString(": WRN")
+	case config.InfoLevel:
+		buf.WriteString(st.green)
+		buf.WriteString(": INF")
+	}
+	if issue.Category != "" {
+		buf.WriteString(": ")
+		buf.WriteString(issue.Category)
+	}
+	if issue.Message != "" {
+		buf.WriteString(": ")
+		buf.WriteString(issue.Message)
+	}
+	buf.WriteString(st.reset)
+	buf.WriteString(" (")
+	buf.WriteString(issueAnalyzers(name, issue))
+	buf.WriteRune(')')
 
-					if line != -1 && line < len(f.Lines) {
-						buf.WriteRune('\n')
-						buf.WriteString(strings.Replace(f.Lines[line-1], "\t", " ", pos))
-						if pos != -1 {
-							buf.Grow(pos)
-							for i := 0; i < pos-1; i++ {
-								buf.WriteRune(' ')
-							}
-							buf.WriteString(colorYellow)
-							buf.WriteRune('^')
-							buf.WriteString(colorReset)
-						}
-					}
-					buf.WriteRune('\n')
-				FIXES:
-					for _, fix := range issue.SuggestedFixes {
-						buf.WriteString("Suggested Fix:")
-						if fix.Message != "" {
-							buf.WriteString(colorRed)
-							buf.WriteRune(' ')
-							buf.WriteString(fix.Message)
-							buf.WriteString(colorReset)
-						}
-						buf.WriteRune('\n')
-						reader := bytes.NewReader(f.Data)
-						fixed := bytes.Buffer{}
-						for _, edit := range fix.Edits {
-							if edit.Filename != filename {
-								// do not support modifications in multiple files for simplicity
-								log.Printf("suggested fix for a file %q modifies another file %q: %#v", filename, edit.Filename, fix)
-								break FIXES
-							}
-							var cur int64
-							cur, err = reader.Seek(0, io.SeekCurrent)
-							if err != nil {
-								log.Printf("seeking on buffer for file %q failed: %+v", filename, err)
-								break FIXES
-							}
-							if l := int64(edit.Start) - cur; l > 0 {
-								b := make([]byte, l)
-								if _, err = reader.Read(b); err != nil {
-									log.Printf("reading from buffer for file %q failed: %+v", filename, err)
-									break FIXES
-								}
-								fixed.Write(b)
-							}
-							fixed.WriteString(edit.New)
-							end := edit.End
-							if end < edit.Start {
-								end = edit.Start
-							}
-							if _, err = reader.Seek(int64(end), io.SeekStart); err != nil {
-								log.Printf("seeking on buffer for file %q failed: %+v", filename, err)
-								break FIXES
-							}
-						}
-						// read remaining data
-						var data []byte
-						data, err = io.ReadAll(reader)
-						if err != nil {
-							log.Printf("reading remaining data from buffer for file %q failed: %+v", filename, err)
-							break
-						}
-						if len(data) > 0 {
-							fixed.Write(data)
-						}
-						d := difflib.UnifiedDiff{
-							A:       f.Lines,
-							B:       difflib.SplitLines(fixed.String()),
-							Context: 1,
-						}
-						var diff string
-						diff, err = difflib.GetUnifiedDiffString(d)
-						if err != nil {
-							log.Printf("getting diff for file %q failed: %+v", filename, err)
-							break FIXES
-						}
-						if diff == "" {
-							continue
-						}
-						fix.Diff = diff
-						lines := difflib.SplitLines(diff)
-						for i := 0; i < len(lines); i++ {
-							reset := false
-							s := lines[i]
-							switch {
-							case strings.HasPrefix(s, "@@"):
-								buf.WriteString(colorPurple)
-								reset = true
-							case strings.HasPrefix(s, "+"):
-								reset = true
-								buf.WriteString(colorGreen)
-							case strings.HasPrefix(s, "-"):
-								reset = true
-								buf.WriteString(colorRed)
-							}
-							buf.WriteString(lines[i])
-							if reset {
-								buf.WriteString(colorReset)
-							}
-						}
-					}
-					if _, err := buf.WriteTo(os.Stdout); err != nil {
-						log.Printf("writing to stdout failed: %+v", err)
-						os.Exit(1)
-					}
-				}()
+	buf.WriteRune('\n')
+	if line != -1 && line <= len(f.Lines) {
+		endLine, endPos := line, pos
+		if issue.End != "" {
+			if endFilename, l, p := parsePosN(issue.End); endFilename == filename && l >= line {
+				endLine, endPos = l, p
 			}
 		}
+		writeSource(buf, f.Lines, line, pos, endLine, endPos, st)
 	}
-	wg.Wait()
-	return
+	for _, text := range issueDetails(issue) {
+		buf.WriteString(text)
+		buf.WriteRune('\n')
+	}
+FIXES:
+	for _, fix := range issue.SuggestedFixes {
+		buf.WriteString("Suggested Fix:")
+		if fix.Message != "" {
+			buf.WriteString(st.red)
+			buf.WriteRune(' ')
+			buf.WriteString(fix.Message)
+			buf.WriteString(st.reset)
+		}
+		buf.WriteRune('\n')
+		reader := bytes.NewReader(f.Data)
+		fixed := bytes.Buffer{}
+		for _, edit := range fix.Edits {
+			if edit.Filename != filename {
+				// do not support modifications in multiple files for simplicity
+				log.Printf("suggested fix for a file %q modifies another file %q: %#v", filename, edit.Filename, fix)
+				break FIXES
+			}
+			var cur int64
+			cur, err = reader.Seek(0, io.SeekCurrent)
+			if err != nil {
+				log.Printf("seeking on buffer for file %q failed: %+v", filename, err)
+				break FIXES
+			}
+			if l := int64(edit.Start) - cur; l > 0 {
+				b := make([]byte, l)
+				if _, err = reader.Read(b); err != nil {
+					log.Printf("reading from buffer for file %q failed: %+v", filename, err)
+					break FIXES
+				}
+				fixed.Write(b)
+			}
+			fixed.WriteString(edit.New)
+			end := edit.End
+			if end < edit.Start {
+				end = edit.Start
+			}
+			if _, err = reader.Seek(int64(end), io.SeekStart); err != nil {
+				log.Printf("seeking on buffer for file %q failed: %+v", filename, err)
+				break FIXES
+			}
+		}
+		// read remaining data
+		var data []byte
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			log.Printf("reading remaining data from buffer for file %q failed: %+v", filename, err)
+			break
+		}
+		if len(data) > 0 {
+			fixed.Write(data)
+		}
+		d := difflib.UnifiedDiff{
+			A:       f.Lines,
+			B:       difflib.SplitLines(fixed.String()),
+			Context: 1,
+		}
+		var diff string
+		diff, err = difflib.GetUnifiedDiffString(d)
+		if err != nil {
+			log.Printf("getting diff for file %q failed: %+v", filename, err)
+			break FIXES
+		}
+		if diff == "" {
+			continue
+		}
+		lines := difflib.SplitLines(diff)
+		for i := 0; i < len(lines); i++ {
+			reset := false
+			s := lines[i]
+			switch {
+			case strings.HasPrefix(s, "@@"):
+				buf.WriteString(st.purple)
+				reset = true
+			case strings.HasPrefix(s, "+"):
+				reset = true
+				buf.WriteString(st.green)
+			case strings.HasPrefix(s, "-"):
+				reset = true
+				buf.WriteString(st.red)
+			}
+			buf.WriteString(lines[i])
+			if reset {
+				buf.WriteString(st.reset)
+			}
+		}
+	}
+}
+
+// writeSource writes the source line of the issue and the context lines around it,
+// the range of the issue is underlined up to the end position or the end of the line
+func writeSource(buf *bytes.Buffer, lines []string, line, pos, endLine, endPos int, st *consoleStyle) {
+	from := line - st.context
+	if from < 1 {
+		from = 1
+	}
+	to := line + st.context
+	if to > len(lines) {
+		to = len(lines)
+	}
+	for l := from; l <= to; l++ {
+		text := strings.TrimRight(lines[l-1], "\r\n")
+		buf.WriteString(expandTabs(text, st.tabWidth))
+		buf.WriteRune('\n')
+		if l != line || pos < 1 || pos-1 > len(text) {
+			continue
+		}
+		end := pos
+		switch {
+		case endLine > line:
+			end = len(text) + 1
+		case endPos > pos && endPos-1 <= len(text):
+			end = endPos
+		}
+		offset := displayWidth(text[:pos-1], 0, st.tabWidth)
+		width := displayWidth(text[pos-1:end-1], offset, st.tabWidth)
+		if width < 1 {
+			width = 1
+		}
+		buf.WriteString(strings.Repeat(" ", offset))
+		buf.WriteString(st.yellow)
+		buf.WriteString(strings.Repeat("^", width))
+		buf.WriteString(st.reset)
+		buf.WriteRune('\n')
+	}
+}
+
+// displayWidth returns the number of the terminal cells to render the text starting at the given cell,
+// the tabs are expanded to the next tab stop, the wide characters take two cells and the combining marks none
+func displayWidth(text string, start, tabWidth int) int {
+	w := start
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			w += tabWidth - w%tabWidth
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		default:
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				w += 2
+			default:
+				w++
+			}
+		}
+	}
+	return w - start
+}
+
+// expandTabs replaces the tabs with the spaces up to the next tab stop
+func expandTabs(text string, tabWidth int) string {
+	if !strings.ContainsRune(text, '\t') {
+		return text
+	}
+	var (
+		b strings.Builder
+		w int
+	)
+	for _, r := range text {
+		if r == '\t' {
+			n := tabWidth - w%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			w += n
+			continue
+		}
+		b.WriteRune(r)
+		w += displayWidth(string(r), w, tabWidth)
+	}
+	return b.String()
+}
+
+// issueDetails returns the related locations and the documentation url of the issue as text lines
+func issueDetails(issue *Issue) []string {
+	var lines []string
+	for _, rel := range issue.Related {
+		text := "related: " + relPosN(rel.PosN)
+		if rel.Message != "" {
+			text += ": " + rel.Message
+		}
+		lines = append(lines, text)
+	}
+	for _, src := range issue.Sources {
+		lines = append(lines, "also "+src.Analyzer+": "+src.Message)
+	}
+	if issue.URL != "" {
+		lines = append(lines, "see: "+issue.URL)
+	}
+	return lines
+}
+
+// issueAnalyzers returns the name of the analyzer along with the analyzers of the merged issues
+func issueAnalyzers(name string, issue *Issue) string {
+	for _, src := range issue.Sources {
+		name += ", " + src.Analyzer
+	}
+	return name
+}
+
+// relPosN returns the position with the file name relative to the working directory
+func relPosN(posN string) string {
+	filename, line, pos := parsePosN(posN)
+	if f, err := getFile(filename); err == nil {
+		filename = f.Filename
+	}
+	if line != -1 {
+		filename += ":" + strconv.Itoa(line)
+		if pos != -1 {
+			filename += ":" + strconv.Itoa(pos)
+		}
+	}
+	return filename
 }
 
 func parsePosN(posN string) (string, int, int) {
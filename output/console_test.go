@@ -0,0 +1,98 @@
+package output_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		text     string
+		start    int
+		expected int
+	}{
+		{name: "ascii", text: "abc", expected: 3},
+		{name: "tab", text: "\tx", expected: 5},
+		{name: "tab after start", text: "\t", start: 2, expected: 2},
+		{name: "wide characters", text: "日本", expected: 4},
+		{name: "combining mark", text: "e\u0301", expected: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, output.DisplayWidth(tt.text, tt.start, 4))
+		})
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "no tabs", text: "abc", expected: "abc"},
+		{name: "tab stop", text: "a\tb", expected: "a   b"},
+		{name: "indentation", text: "\t\tx", expected: "        x"},
+		{name: "after wide character", text: "日\tx", expected: "日  x"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, output.ExpandTabs(tt.text, 4))
+		})
+	}
+}
+
+func TestWriteSource(t *testing.T) {
+	lines := []string{"package a\n", "\tx := 日本 + y\n", "}\n"}
+	for _, tt := range []struct {
+		name     string
+		pos      int
+		endLine  int
+		endPos   int
+		context  int
+		expected string
+	}{
+		{
+			name:     "column",
+			pos:      2,
+			endLine:  2,
+			endPos:   -1,
+			expected: "    x := 日本 + y\n    ^\n",
+		},
+		{
+			name:     "range of wide characters",
+			pos:      7,
+			endLine:  2,
+			endPos:   13,
+			expected: "    x := 日本 + y\n         ^^^^\n",
+		},
+		{
+			name:     "range to the next line",
+			pos:      2,
+			endLine:  3,
+			endPos:   1,
+			expected: "    x := 日本 + y\n    ^^^^^^^^^^^^^\n",
+		},
+		{
+			name:     "context",
+			pos:      2,
+			endLine:  2,
+			endPos:   -1,
+			context:  1,
+			expected: "package a\n    x := 日本 + y\n    ^\n}\n",
+		},
+		{
+			name:     "column after the end of line",
+			pos:      30,
+			endLine:  2,
+			endPos:   -1,
+			expected: "    x := 日本 + y\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, output.WriteSource(lines, 2, tt.pos, tt.endLine, tt.endPos, tt.context, 4))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package output
+
+import "bytes"
+
+// SetModuleDir sets the directory of the main module, the files outside of it are never fixed
+func (m *Modifier) SetModuleDir(dir string) {
+	m.modDir = dir
+}
+
+// DisplayWidth and ExpandTabs expose the rendering of the source code
+var (
+	DisplayWidth = displayWidth
+	ExpandTabs   = expandTabs
+)
+
+// WriteSource returns the source lines of the issue rendered without the colors
+func WriteSource(lines []string, line, pos, endLine, endPos, context, tabWidth int) string {
+	buf := bytes.Buffer{}
+	writeSource(&buf, lines, line, pos, endLine, endPos, &consoleStyle{context: context, tabWidth: tabWidth})
+	return buf.String()
+}
+
+// SetRepoRoot sets the root of the repository, the paths of the sarif and gitlab outputs are relative to it
+func SetRepoRoot(dir string) {
+	repoRoot = func() string { return dir }
+}
+
+// MergeLines exposes the union of the changed lines of a file
+var MergeLines = mergeLines
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// positionRE matches the positions in the messages, e.g. `shadows declaration at line 5` or `a.go:5:2`
+var positionRE = regexp.MustCompile(`\b(line) \d+|(\.go):\d+(?::\d+)?`)
+
+// Fingerprint returns the identity of the issue, which does not depend on the line numbers:
+// the hash of the analyzer, the package, the enclosing declaration, the source line without extra spaces and the message
+// without the positions. The test variants of a package have the same fingerprints as the package itself.
+func Fingerprint(pkg, analyzer string, issue *Issue) string {
+	pkgPath, _, _ := strings.Cut(pkg, " ")
+	filename, line, _ := parsePosN(issue.PosN)
+	var decl, text string
+	if f, err := getFile(filename); err == nil && line > 0 && line <= len(f.Lines) {
+		text = strings.Join(strings.Fields(f.Lines[line-1]), " ")
+		decl = enclosingDecl(filename, f.Data, line)
+	}
+	h := sha256.New()
+	msg := positionRE.ReplaceAllString(issue.Message, "$1$2")
+	for _, s := range []string{analyzer, pkgPath, decl, text, msg} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// issueFingerprint returns the fingerprint of the issue, computing it if the issue does not have one yet
+func issueFingerprint(pkg, analyzer string, issue *Issue) string {
+	if issue.Fingerprint != "" {
+		return issue.Fingerprint
+	}
+	return Fingerprint(pkg, analyzer, issue)
+}
+
+type parsedFile struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+var parsedFiles = sync.Map{}
+
+// enclosingDecl returns the name of the top level declaration containing the given line,
+// the methods are prefixed with the receiver type, e.g. `T.Method`
+func enclosingDecl(filename string, src []byte, line int) string {
+	obj, ok := parsedFiles.Load(filename)
+	if !ok {
+		fset := token.NewFileSet()
+		file, _ := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+		obj, _ = parsedFiles.LoadOrStore(filename, &parsedFile{fset: fset, file: file})
+	}
+	pf := obj.(*parsedFile)
+	if pf.file == nil {
+		return ""
+	}
+	contains := func(node ast.Node) bool {
+		return pf.fset.Position(node.Pos()).Line <= line && line <= pf.fset.Position(node.End()).Line
+	}
+	for _, decl := range pf.file.Decls {
+		if !contains(decl) {
+			continue
+		}
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				return receiverName(decl.Recv.List[0].Type) + "." + decl.Name.Name
+			}
+			return decl.Name.Name
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if !contains(spec) {
+					continue
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					return spec.Name.Name
+				case *ast.ValueSpec:
+					return spec.Names[0].Name
+				}
+			}
+			return decl.Tok.String()
+		}
+	}
+	return ""
+}
+
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
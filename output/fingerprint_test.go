@@ -0,0 +1,40 @@
+package output_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(src), 0o600))
+		return filename
+	}
+	before := write("before.go", "package p\n\nfunc (t *T) F() {\n\tx := 1\n}\n")
+	after := write("after.go", "package p\n\n// F does nothing\n\nfunc (t *T) F() {\n    x  :=  1\n}\n")
+	other := write("other.go", "package p\n\nfunc G() {\n\tx := 1\n}\n")
+
+	const msg = "declaration of x shadows"
+	fingerprint := func(pkg, posN, msg string) string {
+		return output.Fingerprint(pkg, "shadow", &output.Issue{PosN: posN, Message: msg})
+	}
+	expected := fingerprint("pkg", before+":4:2", msg)
+	require.Len(t, expected, 32)
+	require.Equal(t, expected, fingerprint("pkg", after+":6:5", msg), "moved and reformatted line")
+	require.Equal(t, expected, fingerprint("pkg [pkg.test]", before+":4:2", msg), "test variant")
+	require.NotEqual(t, expected, fingerprint("pkg", other+":4:2", msg), "other declaration")
+	require.NotEqual(t, expected, fingerprint("other", before+":4:2", msg), "other package")
+
+	shadows := fingerprint("pkg", before+":4:2", `declaration of "x" shadows declaration at line 5`)
+	require.Equal(t, shadows, fingerprint("pkg", after+":6:5", `declaration of "x" shadows declaration at line 7`), "line in message")
+	require.NotEqual(t, shadows, fingerprint("pkg", before+":4:2", `declaration of "y" shadows declaration at line 5`), "other message")
+	related := fingerprint("pkg", before+":4:2", "x is also declared at a.go:5:2")
+	require.Equal(t, related, fingerprint("pkg", before+":4:2", "x is also declared at a.go:9:1"), "position in message")
+}
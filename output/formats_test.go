@@ -0,0 +1,141 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the output formats")
+
+// formatsDiag returns the issues of the testdata file with the end positions, the related locations,
+// the documentation url and the suggested fix along with the error of an analyzer
+func formatsDiag(t *testing.T) output.Diagnostic {
+	filename, err := filepath.Abs(filepath.Join("testdata", "a.go"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	start := token.Pos(strings.Index(string(data), "%d"))
+	return output.Diagnostic{
+		"pkg": {
+			"errcheck": {Error: "analysis failed"},
+			"printf": {Issues: []*output.Issue{{
+				Message:       "fmt.Printf format %d has arg s of wrong type string",
+				PosN:          filename + ":9:3",
+				End:           filename + ":9:24",
+				URL:           "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/printf",
+				Fingerprint:   "printf-fingerprint",
+				SeverityLevel: config.WarningLevel,
+				SuggestedFixes: []*output.Fix{{
+					Message: "use %s",
+					Edits:   []*output.Edit{{Filename: filename, Start: start, End: start + 2, New: "%s"}},
+				}},
+			}}},
+			"shadow": {Issues: []*output.Issue{{
+				Message:       `declaration of "x" shadows declaration at line 6`,
+				PosN:          filename + ":8:3",
+				End:           filename + ":8:4",
+				Fingerprint:   "shadow-fingerprint",
+				SeverityLevel: config.ErrorLevel,
+				Related:       []*output.Related{{Message: "previous declaration", PosN: filename + ":6:2"}},
+			}}},
+		},
+	}
+}
+
+func TestPrintFormats(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	output.SetRepoRoot(wd)
+	for _, tt := range []struct {
+		name   string
+		golden string
+		print  func(w io.Writer, diag *output.Diagnostic)
+	}{
+		{
+			name:   "sarif",
+			golden: "sarif.json",
+			print: func(w io.Writer, diag *output.Diagnostic) {
+				buf := bytes.Buffer{}
+				output.PrintAsSARIF(&buf, diag)
+				writeSARIFResults(t, w, buf.Bytes())
+			},
+		},
+		{
+			name:   "checkstyle",
+			golden: "checkstyle.xml",
+			print:  output.PrintAsCheckstyle,
+		},
+		{
+			name:   "junit",
+			golden: "junit.xml",
+			print: func(w io.Writer, diag *output.Diagnostic) {
+				packages := []string{"pkg", "pkg [pkg.test]", "pkg.test"}
+				output.PrintAsJUnit(w, diag, packages, []string{"errcheck", "printf", "shadow", "unusedwrite"})
+			},
+		},
+		{
+			name:   "gitlab",
+			golden: "gitlab.json",
+			print:  output.PrintAsGitlab,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diag := formatsDiag(t)
+			buf := bytes.Buffer{}
+			tt.print(&buf, &diag)
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o600))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+// writeSARIFResults writes the sarif document with the rules of the reported issues only,
+// so the golden file does not depend on the list of all analyzers
+func writeSARIFResults(t *testing.T, w io.Writer, data []byte) {
+	var doc struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Invocations []json.RawMessage `json:"invocations"`
+			Results     []map[string]any  `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Len(t, doc.Runs, 1)
+	run := doc.Runs[0]
+	rules := run.Tool.Driver.Rules
+	run.Tool.Driver.Rules = nil
+	for _, result := range run.Results {
+		rule := rules[int(result["ruleIndex"].(float64))]
+		require.Equal(t, result["ruleId"], rule.ID)
+		delete(result, "ruleIndex")
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	require.NoError(t, e.Encode(run))
+}
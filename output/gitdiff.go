@@ -0,0 +1,214 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+// ChangedLines holds the added or modified lines by the absolute file names,
+// a nil set means that the whole file is new
+type ChangedLines map[string]map[int]struct{}
+
+// Contains returns true if the given line of the file is added or modified
+func (c ChangedLines) Contains(filename string, line int) bool {
+	lines, ok := c[filename]
+	if !ok {
+		return false
+	}
+	if lines == nil {
+		return true
+	}
+	_, ok = lines[line]
+	return ok
+}
+
+var hunkRE = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseUnifiedDiff returns the added or modified lines of the unified diff,
+// the file names are joined with the given root directory
+func ParseUnifiedDiff(r io.Reader, root string) (ChangedLines, error) {
+	var (
+		changed  = make(ChangedLines)
+		filename string
+		line     int
+		left     int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if left > 0 {
+			switch {
+			case strings.HasPrefix(text, "+"):
+				if filename != "" {
+					changed[filename][line] = struct{}{}
+				}
+				line++
+				left--
+				continue
+			case strings.HasPrefix(text, " "), text == "":
+				line++
+				left--
+				continue
+			case strings.HasPrefix(text, "-"), strings.HasPrefix(text, `\`):
+				continue
+			}
+			left = 0
+		}
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			name := strings.TrimPrefix(text, "+++ ")
+			if i := strings.IndexByte(name, '\t'); i != -1 {
+				name = name[:i]
+			}
+			if name == "/dev/null" {
+				filename = ""
+				continue
+			}
+			name = strings.TrimPrefix(name, "b/")
+			filename = filepath.Join(root, filepath.FromSlash(name))
+			if _, ok := changed[filename]; !ok {
+				changed[filename] = make(map[int]struct{})
+			}
+		case strings.HasPrefix(text, "@@"):
+			m := hunkRE.FindStringSubmatch(text)
+			if m == nil {
+				return nil, fmt.Errorf("wrong hunk header %q", text)
+			}
+			start, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, err
+			}
+			count := 1
+			if m[2] != "" {
+				if count, err = strconv.Atoi(m[2]); err != nil {
+					return nil, err
+				}
+			}
+			line = start
+			left = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+var changedLines = make(map[string]ChangedLines)
+
+// getChangedLines returns the lines changed since the git reference of the rule or in the patch file of the rule
+func getChangedLines(rule *config.Rule) ChangedLines {
+	key := strings.Join([]string{rule.GitRef, strconv.FormatBool(rule.GitUncommitted), rule.Patch}, "\x00")
+	if v, ok := changedLines[key]; ok {
+		return v
+	}
+
+	root, err := gitRoot()
+	if err != nil {
+		if rule.GitRef != "" {
+			log.Fatalf("getting git root failed: %+v", err)
+		}
+		if root, err = os.Getwd(); err != nil {
+			log.Fatalf("getting working directory failed: %+v", err)
+		}
+	}
+
+	changed := make(ChangedLines)
+	if rule.Patch != "" {
+		f, err := os.Open(rule.Patch)
+		if err != nil {
+			log.Fatalf("opening patch file failed: %+v", err)
+		}
+		changed, err = ParseUnifiedDiff(f, root)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatalf("parsing patch file %q failed: %+v", rule.Patch, err)
+		}
+	}
+	if rule.GitRef != "" {
+		// the prefixes are set explicitly, so the diff.noprefix and diff.mnemonicPrefix options do not change the file names
+		args := []string{"diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "-U0"}
+		if rule.GitUncommitted {
+			// compare the working tree with the merge base, so the staged and unstaged changes are included
+			base, err := git("merge-base", rule.GitRef, "HEAD")
+			if err != nil {
+				log.Fatalf("getting merge base for reference %q failed: %+v", rule.GitRef, err)
+			}
+			args = append(args, strings.TrimSpace(string(base)))
+		} else {
+			args = append(args, rule.GitRef+"...HEAD")
+		}
+		out, err := git(args...)
+		if err != nil {
+			log.Fatalf("getting changed lines for reference %q failed: %+v", rule.GitRef, err)
+		}
+		lines, err := ParseUnifiedDiff(bytes.NewReader(out), root)
+		if err != nil {
+			log.Fatalf("parsing git diff for reference %q failed: %+v", rule.GitRef, err)
+		}
+		for filename, v := range lines {
+			if prev, ok := changed[filename]; ok {
+				v = mergeLines(prev, v)
+			}
+			changed[filename] = v
+		}
+		if rule.GitUncommitted {
+			out, err = git("ls-files", "--others", "--exclude-standard", "--full-name")
+			if err != nil {
+				log.Fatalf("getting untracked files failed: %+v", err)
+			}
+			for _, name := range strings.Split(string(out), "\n") {
+				if name = strings.TrimSpace(name); name != "" {
+					changed[filepath.Join(root, filepath.FromSlash(name))] = nil
+				}
+			}
+		}
+	}
+	changedLines[key] = changed
+	return changed
+}
+
+// mergeLines returns the union of the changed lines of a file, nil if any of the sets is the whole file
+func mergeLines(a, b map[int]struct{}) map[int]struct{} {
+	if a == nil || b == nil {
+		return nil
+	}
+	for line := range b {
+		a[line] = struct{}{}
+	}
+	return a
+}
+
+func gitRoot() (string, error) {
+	out, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Env = os.Environ()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed with error '%w' and output: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package output_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	root := filepath.FromSlash("/root")
+	for _, tt := range []struct {
+		name     string
+		diff     string
+		expected output.ChangedLines
+	}{
+		{
+			name:     "empty",
+			expected: output.ChangedLines{},
+		},
+		{
+			name: "zero context",
+			diff: `diff --git a/main.go b/main.go
+index 0d8c5f1..e6a1a5e 100644
+--- a/main.go
++++ b/main.go
+@@ -3,0 +4,2 @@ import "fmt"
++// comment
++// another comment
+@@ -10 +12 @@ func main() {
+-	fmt.Println("a")
++	fmt.Println("b")
+@@ -20,2 +22,0 @@ func main() {
+-	x := 1
+-	_ = x
+`,
+			expected: output.ChangedLines{
+				filepath.Join(root, "main.go"): {4: {}, 5: {}, 12: {}},
+			},
+		},
+		{
+			name: "with context and new file",
+			diff: `--- a/pkg/file.go
++++ b/pkg/file.go
+@@ -1,4 +1,5 @@
+ package pkg
+-
++// doc
++
+ func F() {
+ }
+--- /dev/null
++++ b/pkg/new.go
+@@ -0,0 +1,2 @@
++package pkg
++
+`,
+			expected: output.ChangedLines{
+				filepath.Join(root, "pkg", "file.go"): {2: {}, 3: {}},
+				filepath.Join(root, "pkg", "new.go"):  {1: {}, 2: {}},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := output.ParseUnifiedDiff(strings.NewReader(tt.diff), root)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		a, b     map[int]struct{}
+		expected map[int]struct{}
+	}{
+		{
+			name:     "union",
+			a:        map[int]struct{}{1: {}, 2: {}},
+			b:        map[int]struct{}{2: {}, 5: {}},
+			expected: map[int]struct{}{1: {}, 2: {}, 5: {}},
+		},
+		{
+			name: "whole file first",
+			b:    map[int]struct{}{2: {}},
+		},
+		{
+			name: "whole file second",
+			a:    map[int]struct{}{2: {}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, output.MergeLines(tt.a, tt.b))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -11,109 +12,139 @@ import (
 	"github.com/sv-tools/gochecker/config"
 )
 
-func PrintAsGithub(diag *Diagnostic) (ret bool) {
-	// print console output for people
-	if _, err := os.Stdout.WriteString("::group::console format\n"); err != nil {
-		log.Printf("writing to stdout failed: %+v", err)
-		os.Exit(1)
-	}
-	ret = PrintAsConsole(diag)
-	if _, err := os.Stdout.WriteString("::endgroup::\n"); err != nil {
-		log.Printf("writing to stdout failed: %+v", err)
-		os.Exit(1)
+// PrintAsGithub prints the console output of each group of the issues, by packages by default,
+// in a collapsible section and then all issues as annotations in the same order
+func PrintAsGithub(w io.Writer, conf *config.Config, diag *Diagnostic) (ret bool) {
+	group := conf.Group
+	if group == "" {
+		group = config.PackageKey
 	}
+	entries := SortIssues(diag, conf.Sort, group)
+	// the logs of GitHub Actions support the colors, but they are not a terminal
+	st := newConsoleStyle(conf, conf.Color == config.ColorAlways || conf.Color == config.ColorAuto && os.Getenv("NO_COLOR") == "")
 
-	wg := sync.WaitGroup{}
-	for _, pkg := range *diag {
-		for name, obj := range pkg {
-			if obj.Error != "" {
-				buf := bytes.Buffer{}
-				buf.WriteString("::error:: ")
-				buf.WriteString(name)
-				buf.WriteString(": ")
-				buf.WriteString(obj.Error)
-				buf.WriteRune('\n')
-				if _, err := buf.WriteTo(os.Stdout); err != nil {
-					log.Printf("writing to stdout failed: %+v", err)
-					os.Exit(1)
-				}
+	// print console output for people
+	for start := 0; start < len(entries); {
+		key := entries[start].Key(group)
+		end := start + 1
+		for end < len(entries) && entries[end].Key(group) == key {
+			end++
+		}
+		if group == config.FileKey {
+			if f, err := getFile(key); err == nil {
+				key = f.Filename
 			}
-			for _, issue := range obj.Issues {
-				wg.Add(1)
-				name := name
-				issue := issue
-				go func() {
-					defer wg.Done()
-
-					filename, line, pos := parsePosN(issue.PosN)
-					f, err := getFile(filename)
-					if err != nil {
-						log.Printf("reading file %q failed: %+v", filename, err)
-						return
-					}
-
-					buf := bytes.Buffer{}
-					switch issue.SeverityLevel {
-					case config.ErrorLevel:
-						buf.WriteString("::error file=")
-					case config.WarningLevel:
-						buf.WriteString("::warning file=")
-					case config.InfoLevel:
-						buf.WriteString("::notice file=")
-					}
-					buf.WriteString(f.Filename)
-					if line != -1 {
-						buf.WriteString(",line=")
-						buf.WriteString(strconv.Itoa(line))
-						if pos != -1 {
-							buf.WriteString(",col=")
-							buf.WriteString(strconv.Itoa(pos))
-						}
-					}
-					buf.WriteString("::")
-					if issue.Category != "" {
-						buf.WriteString(issue.Category)
-						buf.WriteString(": ")
-					}
-					if issue.Message != "" {
-						buf.WriteString(issue.Message)
-					}
-					buf.WriteString(" (")
-					buf.WriteString(name)
-					buf.WriteRune(')')
-					if line != -1 && line < len(f.Lines) {
-						buf.WriteString("%0A")
-						buf.WriteString(strings.Replace(strings.TrimSuffix(f.Lines[line-1], "\n"), "\t", " ", pos))
-						if pos != -1 {
-							buf.WriteString("%0A")
-							buf.Grow(pos)
-							for i := 0; i < pos-1; i++ {
-								buf.WriteRune(' ')
-							}
-							buf.WriteRune('^')
-						}
-					}
-					for _, fix := range issue.SuggestedFixes {
-						buf.WriteString("%0A")
-						buf.WriteString("Suggested Fix:")
-						if fix.Message != "" {
-							buf.WriteRune(' ')
-							buf.WriteString(fix.Message)
-						}
-						buf.WriteString("%0A```diff%0A")
-						buf.WriteString(strings.ReplaceAll(fix.Diff, "\n", "%0A"))
-						buf.WriteString("```")
-					}
+		}
+		if _, err := io.WriteString(w, "::group::"+key+"\n"); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+		if printEntriesAsConsole(w, entries[start:end], "", st) {
+			ret = true
+		}
+		if _, err := io.WriteString(w, "::endgroup::\n"); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+		start = end
+	}
 
-					buf.WriteRune('\n')
-					if _, err = buf.WriteTo(os.Stdout); err != nil {
-						log.Printf("writing to stdout failed: %+v", err)
-						os.Exit(1)
-					}
-				}()
-			}
+	// the annotations hold the issue line only, without the colors
+	src := &consoleStyle{tabWidth: st.tabWidth}
+	bufs := make([]bytes.Buffer, len(entries))
+	wg := sync.WaitGroup{}
+	for i, e := range entries {
+		if e.Error != "" {
+			bufs[i].WriteString("::error:: ")
+			bufs[i].WriteString(e.Analyzer)
+			bufs[i].WriteString(": ")
+			bufs[i].WriteString(e.Error)
+			bufs[i].WriteRune('\n')
+			continue
 		}
+		wg.Add(1)
+		go func(buf *bytes.Buffer, e *Entry) {
+			defer wg.Done()
+			writeGithubIssue(buf, e.Analyzer, e.Issue, src)
+		}(&bufs[i], e)
 	}
 	wg.Wait()
+	for i := range bufs {
+		if _, err := bufs[i].WriteTo(w); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+	}
 	return
 }
+
+// writeGithubIssue renders the issue as an annotation with the source line and the suggested fixes
+func writeGithubIssue(buf *bytes.Buffer, name string, issue *Issue, st *consoleStyle) {
+	filename, line, pos := parsePosN(issue.PosN)
+	f, err := getFile(filename)
+	if err != nil {
+		log.Printf("reading file %q failed: %+v", filename, err)
+		return
+	}
+
+	switch issue.SeverityLevel {
+	case config.ErrorLevel:
+		buf.WriteString("::error file=")
+	case config.WarningLevel:
+		buf.WriteString("::warning file=")
+	case config.InfoLevel:
+		buf.WriteString("::notice file=")
+	}
+	buf.WriteString(f.Filename)
+	if line != -1 {
+		buf.WriteString(",line=")
+		buf.WriteString(strconv.Itoa(line))
+		if pos != -1 {
+			buf.WriteString(",col=")
+			buf.WriteString(strconv.Itoa(pos))
+		}
+	}
+	endLine, endPos := line, pos
+	if endFilename, l, p := parsePosN(issue.End); line != -1 && issue.End != "" && endFilename == filename && l >= line {
+		endLine, endPos = l, p
+		buf.WriteString(",endLine=")
+		buf.WriteString(strconv.Itoa(endLine))
+		if endPos != -1 {
+			buf.WriteString(",endColumn=")
+			buf.WriteString(strconv.Itoa(endPos))
+		}
+	}
+	buf.WriteString("::")
+	if issue.Category != "" {
+		buf.WriteString(issue.Category)
+		buf.WriteString(": ")
+	}
+	if issue.Message != "" {
+		buf.WriteString(issue.Message)
+	}
+	buf.WriteString(" (")
+	buf.WriteString(issueAnalyzers(name, issue))
+	buf.WriteRune(')')
+	if line != -1 && line <= len(f.Lines) {
+		source := bytes.Buffer{}
+		writeSource(&source, f.Lines, line, pos, endLine, endPos, st)
+		buf.WriteString("%0A")
+		buf.WriteString(strings.ReplaceAll(strings.TrimSuffix(source.String(), "\n"), "\n", "%0A"))
+	}
+	for _, text := range issueDetails(issue) {
+		buf.WriteString("%0A")
+		buf.WriteString(text)
+	}
+	for _, fix := range issue.SuggestedFixes {
+		buf.WriteString("%0A")
+		buf.WriteString("Suggested Fix:")
+		if fix.Message != "" {
+			buf.WriteRune(' ')
+			buf.WriteString(fix.Message)
+		}
+		buf.WriteString("%0A```diff%0A")
+		buf.WriteString(strings.ReplaceAll(fix.Diff, "\n", "%0A"))
+		buf.WriteString("```")
+	}
+	buf.WriteRune('\n')
+}
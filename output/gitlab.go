@@ -0,0 +1,124 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+type (
+	gitlabIssue struct {
+		Description string         `json:"description"`
+		CheckName   string         `json:"check_name"`
+		Fingerprint string         `json:"fingerprint"`
+		Severity    string         `json:"severity"`
+		Content     *gitlabContent `json:"content,omitempty"`
+		Location    gitlabLocation `json:"location"`
+	}
+	gitlabContent struct {
+		Body string `json:"body"`
+	}
+	gitlabLocation struct {
+		Path  string      `json:"path"`
+		Lines gitlabLines `json:"lines"`
+	}
+	gitlabLines struct {
+		Begin int `json:"begin"`
+		End   int `json:"end,omitempty"`
+	}
+)
+
+// PrintAsGitlab prints all issues as a GitLab Code Quality report.
+// The fingerprints do not depend on the line numbers, so the merge requests can show the new and resolved issues.
+// The errors of the analyzers are reported as blockers.
+func PrintAsGitlab(w io.Writer, diag *Diagnostic) {
+	issues := make([]*gitlabIssue, 0)
+	seen := make(map[string]int)
+	for _, pkgName := range sortedKeys(*diag) {
+		pkg := (*diag)[pkgName]
+		for _, name := range sortedKeys(pkg) {
+			obj := pkg[name]
+			if obj.Error != "" {
+				issues = append(issues, &gitlabIssue{
+					Description: pkgName + ": " + obj.Error,
+					CheckName:   name,
+					Fingerprint: gitlabFingerprint(seen, name, pkgName, obj.Error),
+					Severity:    "blocker",
+					Location:    gitlabLocation{Path: pkgName, Lines: gitlabLines{Begin: 1}},
+				})
+				continue
+			}
+			for _, issue := range obj.Issues {
+				_, line, _ := parsePosN(issue.PosN)
+				if line < 1 {
+					line = 1
+				}
+				description := issue.Message
+				if issue.Category != "" {
+					description = issue.Category + ": " + description
+				}
+				gi := gitlabIssue{
+					Description: description,
+					CheckName:   name,
+					Fingerprint: gitlabFingerprint(seen, issueFingerprint(pkgName, name, issue)),
+					Severity:    gitlabSeverity(issue.SeverityLevel),
+					Location:    gitlabLocation{Path: gitlabPath(issue.PosN), Lines: gitlabLines{Begin: line}},
+				}
+				if _, endLine, _ := parsePosN(issue.End); endLine > line {
+					gi.Location.Lines.End = endLine
+				}
+				if details := issueDetails(issue); len(details) > 0 {
+					gi.Content = &gitlabContent{Body: strings.Join(details, "\n\n")}
+				}
+				issues = append(issues, &gi)
+			}
+		}
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(issues); err != nil {
+		log.Fatalf("gitlab ouput failed: %+v", err)
+	}
+}
+
+// gitlabFingerprint returns the hash of the given parts and the number of the previous issues with the same parts,
+// so the identical issues have the unique fingerprints
+func gitlabFingerprint(seen map[string]int, parts ...string) string {
+	h := sha256.New()
+	for _, s := range parts {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	key := string(h.Sum(nil))
+	n := seen[key]
+	seen[key] = n + 1
+	h.Write([]byte(strconv.Itoa(n)))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// gitlabPath returns the path of the file relative to the root of the repository, as GitLab expects
+func gitlabPath(posN string) string {
+	filename, _, _ := parsePosN(posN)
+	if path, ok := repoPath(filename); ok {
+		return path
+	}
+	return issuePath(posN)
+}
+
+func gitlabSeverity(severity string) string {
+	switch severity {
+	case config.WarningLevel:
+		return "minor"
+	case config.InfoLevel:
+		return "info"
+	default:
+		return "major"
+	}
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+// Prompt asks the user whether to apply the suggested fixes of each issue
+type Prompt struct {
+	r    *bufio.Reader
+	w    io.Writer
+	st   *consoleStyle
+	all  map[string]struct{}
+	quit bool
+}
+
+// NewPrompt returns a prompt reading the answers from r and printing the issues with the diffs of the fixes to w
+func NewPrompt(r io.Reader, w io.Writer, conf *config.Config) *Prompt {
+	return &Prompt{
+		r:   bufio.NewReader(r),
+		w:   w,
+		st:  newConsoleStyle(conf, useColor(conf.Color, w)),
+		all: make(map[string]struct{}),
+	}
+}
+
+// Ask prints the issue and returns true if the fixes must be applied.
+// The answers are: `y` to accept, `n` to skip, `a` to accept all fixes of the analyzer without asking
+// and `q` to skip all remaining fixes.
+func (p *Prompt) Ask(analyzer string, issue *Issue) bool {
+	if p.quit {
+		return false
+	}
+	if _, ok := p.all[analyzer]; ok {
+		return true
+	}
+	buf := bytes.Buffer{}
+	writeConsoleIssue(&buf, analyzer, issue, p.st)
+	if _, err := buf.WriteTo(p.w); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+	for {
+		if _, err := io.WriteString(p.w, "Apply the fix? [y]es, [n]o, [a]ll for "+analyzer+", [q]uit: "); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+		answer, err := p.r.ReadString('\n')
+		if err != nil && answer == "" {
+			// no more answers, e.g. stdin is closed
+			p.quit = true
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "a", "all":
+			p.all[analyzer] = struct{}{}
+			return true
+		case "q", "quit":
+			p.quit = true
+			return false
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+)
+
+// PrintAsJSON prints all issues as a single indented json document
+func PrintAsJSON(w io.Writer, diag *Diagnostic) {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(diag); err != nil {
+		log.Fatalf("json ouput failed: %+v", err)
+	}
+}
+
+// PrintAsJSONLines prints the issues of each package as a separate json object on a single line
+func PrintAsJSONLines(w io.Writer, diag *Diagnostic) {
+	e := json.NewEncoder(w)
+	for pkgName, pkg := range *diag {
+		if err := e.Encode(Diagnostic{pkgName: pkg}); err != nil {
+			log.Fatalf("json lines ouput failed: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name          `xml:"testsuites"`
+		Name     string            `xml:"name,attr"`
+		Tests    int               `xml:"tests,attr"`
+		Failures int               `xml:"failures,attr"`
+		Errors   int               `xml:"errors,attr"`
+		Suites   []*junitTestSuite `xml:"testsuite"`
+	}
+	junitTestSuite struct {
+		Name      string           `xml:"name,attr"`
+		Tests     int              `xml:"tests,attr"`
+		Failures  int              `xml:"failures,attr"`
+		Errors    int              `xml:"errors,attr"`
+		TestCases []*junitTestCase `xml:"testcase"`
+	}
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Error     *junitFailure `xml:"error,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// PrintAsJUnit prints all issues as a junit xml document: each analyzed package is a test suite and each enabled
+// analyzer is a test case, which fails if the analyzer reported an issue with the error severity level,
+// the warning and info issues are printed to the system output of the test case.
+// The packages synthesized by `go test`, e.g. `pkg.test`, are skipped if they have no issues.
+func PrintAsJUnit(w io.Writer, diag *Diagnostic, packages, analyzers []string) {
+	analyzed := make([]string, 0, len(packages))
+	for _, pkgName := range packages {
+		if !strings.HasSuffix(pkgName, ".test") {
+			analyzed = append(analyzed, pkgName)
+		}
+	}
+	out := junitTestSuites{Name: "gochecker"}
+	for _, pkgName := range union(analyzed, sortedKeys(*diag)) {
+		pkg := (*diag)[pkgName]
+		suite := junitTestSuite{Name: pkgName}
+		for _, name := range union(analyzers, sortedKeys(pkg)) {
+			tc := junitTestCase{Name: name, ClassName: pkgName}
+			suite.Tests++
+			obj, ok := pkg[name]
+			if !ok {
+				suite.TestCases = append(suite.TestCases, &tc)
+				continue
+			}
+			if obj.Error != "" {
+				tc.Error = &junitFailure{Message: obj.Error, Type: "error"}
+				suite.Errors++
+				suite.TestCases = append(suite.TestCases, &tc)
+				continue
+			}
+			var failures, others []string
+			for _, issue := range obj.Issues {
+				line := junitIssue(issue)
+				if issue.SeverityLevel == config.ErrorLevel {
+					failures = append(failures, line)
+				} else {
+					others = append(others, line)
+				}
+			}
+			if len(failures) > 0 {
+				tc.Failure = &junitFailure{
+					Message: strconv.Itoa(len(failures)) + " issue(s) found",
+					Type:    config.ErrorLevel,
+					Text:    strings.Join(failures, "\n"),
+				}
+				suite.Failures++
+			}
+			tc.SystemOut = strings.Join(others, "\n")
+			suite.TestCases = append(suite.TestCases, &tc)
+		}
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Errors += suite.Errors
+		out.Suites = append(out.Suites, &suite)
+	}
+
+	data, err := xml.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		log.Fatalf("junit ouput failed: %+v", err)
+	}
+	if _, err := io.WriteString(w, xml.Header+string(data)+"\n"); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+}
+
+// union returns the sorted unique names of both lists
+func union(a, b []string) []string {
+	names := make(map[string]struct{}, len(a)+len(b))
+	for _, name := range append(append([]string(nil), a...), b...) {
+		names[name] = struct{}{}
+	}
+	return sortedKeys(names)
+}
+
+// junitIssue formats the issue as `file:line:column: severity: message`
+func junitIssue(issue *Issue) string {
+	filename, line, pos := parsePosN(issue.PosN)
+	if f, err := getFile(filename); err == nil {
+		filename = f.Filename
+	}
+	buf := strings.Builder{}
+	buf.WriteString(filename)
+	if line != -1 {
+		buf.WriteString(":" + strconv.Itoa(line))
+		if pos != -1 {
+			buf.WriteString(":" + strconv.Itoa(pos))
+		}
+	}
+	buf.WriteString(": ")
+	buf.WriteString(issue.SeverityLevel)
+	buf.WriteString(": ")
+	if issue.Category != "" {
+		buf.WriteString(issue.Category)
+		buf.WriteString(": ")
+	}
+	buf.WriteString(issue.Message)
+	for _, text := range issueDetails(issue) {
+		buf.WriteString("\n  ")
+		buf.WriteString(text)
+	}
+	return buf.String()
+}
@@ -1,88 +1,432 @@
 package output
 
 import (
-	"bytes"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/sv-tools/gochecker/config"
 )
 
-var nolintRE = regexp.MustCompile(`//\s*nolint`)
+// Modifier applies the severity, exclude, nolint and baseline rules to the issues package by package
+// and collects the suggested fixes to be applied at the end of the run
+type Modifier struct {
+	conf      *config.Config
+	baseline  *Baseline
+	nolint    *nolinter
+	toFix     map[string][]*Edit
+	packages  map[string]struct{}
+	files     map[string]string
+	seen      map[string]struct{}
+	fixable   Diagnostic
+	conflicts Diagnostic
+	retry     map[string]int
+	applied   map[string][]*Entry
+	typeCheck TypeChecker
+	modDir    string
+	prompt    *Prompt
+	summary   *Summary
+}
 
-func Modify(conf *config.Config, diag *Diagnostic) {
-	toFix := make(map[string][]*Edit)
-	toDeletePkg := make([]string, 0, len(*diag))
-	for pkgName, pkg := range *diag {
-		toDeleteAnalyzer := make([]string, 0, len(pkg))
+func NewModifier(conf *config.Config) *Modifier {
+	m := Modifier{
+		conf:      conf,
+		nolint:    newNolinter(&conf.Nolint),
+		toFix:     make(map[string][]*Edit),
+		packages:  make(map[string]struct{}),
+		files:     make(map[string]string),
+		seen:      make(map[string]struct{}),
+		fixable:   make(Diagnostic),
+		conflicts: make(Diagnostic),
+		applied:   make(map[string][]*Entry),
+		summary:   NewSummary(),
+	}
+	if conf.Fix && conf.Interactive {
+		m.prompt = NewPrompt(os.Stdin, os.Stderr, conf)
+	}
+	if conf.Baseline != "" {
+		var err error
+		m.baseline, err = LoadBaseline(conf.Baseline)
+		if err != nil {
+			log.Fatalf("loading baseline failed: %+v", err)
+		}
+	}
+	return &m
+}
+
+// SetTypeChecker sets the function to type-check the packages of the fixed files,
+// the fixes breaking the packages are reverted
+func (m *Modifier) SetTypeChecker(fn TypeChecker) {
+	m.typeCheck = fn
+}
+
+// Retry returns a modifier, which applies the fixes of the conflicted issues only without asking the user,
+// who has already accepted them, the retried issues are counted in the summary of the modifier
+func (m *Modifier) Retry(conf *config.Config) *Modifier {
+	r := NewModifier(conf)
+	r.prompt = nil
+	r.typeCheck = m.typeCheck
+	r.modDir = m.modDir
+	r.summary = m.summary
+	r.retry = make(map[string]int)
+	for _, pkg := range m.conflicts {
 		for analyzerName, obj := range pkg {
-			if obj.Error != "" {
-				continue
-			}
-			tmp := make([]*Issue, 0, len(obj.Issues))
 			for _, issue := range obj.Issues {
-				setSeverityLevel(conf.Severity, pkgName, analyzerName, issue)
-				switch {
-				case isNolint(issue): // remove issues with nolint comment
-				case isExcluded(conf.Exclude, pkgName, analyzerName, issue):
-				case conf.Fix && len(issue.SuggestedFixes) > 0: // must be last in the order, so other rules are applied
-					for _, fix := range issue.SuggestedFixes {
-						for _, edit := range fix.Edits {
-							toFix[edit.Filename] = append(toFix[edit.Filename], edit)
-						}
-					}
-				default:
-					tmp = append(tmp, issue)
+				r.retry[retryKey(analyzerName, issue)]++
+			}
+		}
+	}
+	return r
+}
+
+// Package modifies the issues of the given package in place and returns false if no issues left.
+// The issues already reported for another variant of the package, e.g. with tests, are removed.
+// The go files of the package are checked for the unused nolint directives at the end of the run.
+// It is not safe for concurrent use.
+func (m *Modifier) Package(pkgName string, files []string, pkg map[string]*IssuesOrError) bool {
+	m.packages[pkgName] = struct{}{}
+	failed := false
+	toDeleteAnalyzer := make([]string, 0, len(pkg))
+	for analyzerName, obj := range pkg {
+		if obj.Error != "" {
+			failed = true
+			continue
+		}
+		tmp := make([]*Issue, 0, len(obj.Issues))
+		for _, issue := range obj.Issues {
+			if m.retry != nil {
+				key := retryKey(analyzerName, issue)
+				if m.retry[key] == 0 {
+					continue
 				}
+				// the conflicted issue is retried for a single variant of the package
+				m.retry[key]--
 			}
-			if len(tmp) == 0 {
-				toDeleteAnalyzer = append(toDeleteAnalyzer, analyzerName)
-			} else {
-				pkg[analyzerName].Issues = tmp
+			if issue.Fingerprint == "" {
+				issue.Fingerprint = Fingerprint(pkgName, analyzerName, issue)
+			}
+			setSeverityLevel(m.conf.Severity, pkgName, analyzerName, issue)
+			key := analyzerName + "\x00" + issue.PosN + "\x00" + issue.Message
+			_, duplicate := m.seen[key]
+			m.seen[key] = struct{}{}
+			switch {
+			case duplicate:
+				m.summary.Suppressed.Duplicate++
+			case m.nolint.isNolint(analyzerName, issue): // remove issues with nolint comment
+				m.summary.Suppressed.Nolint++
+			case m.isExcluded(pkgName, analyzerName, issue):
+			case m.baseline != nil && m.baseline.Match(pkgName, analyzerName, issue):
+				m.summary.Suppressed.Baseline++
+			case m.conf.Fix && len(issue.SuggestedFixes) > 0 && m.isFixAllowed(analyzerName, issue): // must be last in the order, so other rules are applied
+				m.fixable.addIssue(pkgName, analyzerName, issue)
+			default:
+				setFixDiffs(issue)
+				tmp = append(tmp, issue)
 			}
 		}
-		for _, name := range toDeleteAnalyzer {
-			delete(pkg, name)
+		if len(tmp) == 0 {
+			toDeleteAnalyzer = append(toDeleteAnalyzer, analyzerName)
+		} else {
+			obj.Issues = tmp
 		}
-		if len(pkg) == 0 {
-			toDeletePkg = append(toDeletePkg, pkgName)
+	}
+	for _, name := range toDeleteAnalyzer {
+		delete(pkg, name)
+	}
+	if m.conf.Merge {
+		mergeIssues(pkg)
+	}
+	for analyzerName, obj := range pkg {
+		for _, issue := range obj.Issues {
+			m.summary.Add(pkgName, analyzerName, issue)
 		}
 	}
-	for _, name := range toDeletePkg {
-		delete(*diag, name)
+	if !failed {
+		// the directives of a file shared by several packages (e.g. with tests) are checked once
+		for _, filename := range files {
+			if _, ok := m.files[filename]; !ok {
+				m.files[filename] = pkgName
+			}
+		}
+	}
+	return len(pkg) > 0
+}
+
+// Finish returns the issues of the nolint check if it is enabled and the issues with the fixes skipped by the user,
+// applies all accepted suggested fixes and reports the baseline entries, which no longer exist
+func (m *Modifier) Finish() Diagnostic {
+	diag := make(Diagnostic)
+	if m.conf.Nolint.Check {
+		diag = m.nolint.check(m.files, m.conf.Analyzers)
+		for pkgName, pkg := range diag {
+			if !m.Package(pkgName, nil, pkg) {
+				delete(diag, pkgName)
+			}
+		}
 	}
-	if len(toFix) > 0 {
-		ApplySuggestedFixes(toFix)
+	m.acceptFixes(diag)
+	if len(m.toFix) > 0 {
+		m.writeFixes(diag)
+		m.toFix = make(map[string][]*Edit)
 	}
+	if m.baseline != nil {
+		stale := m.baseline.Stale(m.packages)
+		if len(stale) > 0 {
+			log.Printf("%d baseline entries no longer exist, please update the baseline file %q:", len(stale), m.conf.Baseline)
+			for _, issue := range stale {
+				log.Printf("  %s: %s (%s)", issue.Path, issue.Message, issue.Analyzer)
+			}
+		}
+	}
+	return diag
 }
 
-func isNolint(issue *Issue) bool {
-	filename, line, _ := parsePosN(issue.PosN)
-	f, err := getFile(filename)
-	if err != nil {
-		log.Printf("reading file %q failed: %+v", filename, err)
-		os.Exit(1)
+// acceptFixes collects the edits of the fixable issues in the order of the files and the lines,
+// the issues skipped in the interactive mode and the issues with the fixes written to the patch only
+// are added to the diag to be reported and the issues with the fixes overlapping the already collected edits are kept as the conflicts
+func (m *Modifier) acceptFixes(diag Diagnostic) {
+	ambiguous := 0
+	for _, e := range SortIssues(&m.fixable, config.DefaultSort, "") {
+		fix := m.selectFix(e.Analyzer, e.Issue)
+		reason := ""
+		if fix == nil {
+			ambiguous++
+		} else if reason = m.unsafeFix(fix); reason != "" {
+			log.Printf("%s: the fix is not applied: %s (%s)", relPosN(e.Issue.PosN), reason, e.Analyzer)
+		}
+		if fix == nil || reason != "" || m.prompt != nil && !m.prompt.Ask(e.Analyzer, withFix(e.Issue, fix)) {
+			setFixDiffs(e.Issue)
+			m.summary.Add(e.Package, e.Analyzer, e.Issue)
+			diag.addIssue(e.Package, e.Analyzer, e.Issue)
+			continue
+		}
+		if m.isConflicted(fix) {
+			m.conflicts.addIssue(e.Package, e.Analyzer, e.Issue)
+			continue
+		}
+		for _, edit := range fix.Edits {
+			if n := len(m.applied[edit.Filename]); n == 0 || m.applied[edit.Filename][n-1] != e {
+				m.applied[edit.Filename] = append(m.applied[edit.Filename], e)
+			}
+			m.toFix[edit.Filename] = append(m.toFix[edit.Filename], edit)
+		}
+		if m.conf.DryRun || m.conf.Patch != "" {
+			// the files are not changed, so the issue is still pending
+			setFixDiffs(e.Issue)
+			m.summary.Add(e.Package, e.Analyzer, e.Issue)
+			m.summary.Patched++
+			diag.addIssue(e.Package, e.Analyzer, e.Issue)
+			continue
+		}
+		m.summary.Fixed++
+	}
+	if ambiguous > 0 {
+		log.Printf("%d issues are not fixed because none or several of their alternative fixes are selected by fix_alternatives", ambiguous)
+	}
+	m.fixable = make(Diagnostic)
+}
+
+// isFixAllowed returns true if the fix section of the config allows to fix the issue of the analyzer,
+// the paths must match every file changed by the suggested fixes
+func (m *Modifier) isFixAllowed(analyzer string, issue *Issue) bool {
+	fix := &m.conf.FixConfig
+	if len(fix.Analyzers) > 0 && !slices.Contains(fix.Analyzers, analyzer) {
+		return false
+	}
+	if len(fix.Severity) > 0 && !slices.Contains(fix.Severity, issue.SeverityLevel) {
+		return false
+	}
+	if len(fix.PathsRE) == 0 {
+		return true
+	}
+	for _, suggested := range issue.SuggestedFixes {
+		for _, edit := range suggested.Edits {
+			if !slices.ContainsFunc(fix.PathsRE, func(re *regexp.Regexp) bool {
+				return re.MatchString(edit.Filename)
+			}) {
+				return false
+			}
+		}
 	}
-	return line != -1 && line < len(f.Lines) && nolintRE.MatchString(f.Lines[line-1])
+	return true
 }
 
-func isExcluded(rules []*config.Rule, pkg, analyzer string, issue *Issue) bool {
-	for _, rule := range rules {
-		if matchRule(rule, pkg, analyzer, issue) {
-			return true
+// selectFix returns the only fix of the issue to be applied, because the suggested fixes are the alternatives.
+// The first fix is used, unless the first rule of the analyzer in fix_alternatives selects another one by the message.
+// Nil is returned if the rule matches none or several fixes, the single fix is always used.
+func (m *Modifier) selectFix(analyzer string, issue *Issue) *Fix {
+	if len(issue.SuggestedFixes) == 1 {
+		return issue.SuggestedFixes[0]
+	}
+	for _, alt := range m.conf.FixAlternatives {
+		if alt.MessageRE == nil || alt.Analyzer != "" && alt.Analyzer != analyzer {
+			continue
 		}
+		var selected *Fix
+		for _, fix := range issue.SuggestedFixes {
+			if !alt.MessageRE.MatchString(fix.Message) {
+				continue
+			}
+			if selected != nil {
+				return nil
+			}
+			selected = fix
+		}
+		return selected
+	}
+	return issue.SuggestedFixes[0]
+}
+
+// withFix returns a copy of the issue with the given fix only
+func withFix(issue *Issue, fix *Fix) *Issue {
+	tmp := *issue
+	tmp.SuggestedFixes = []*Fix{fix}
+	return &tmp
+}
+
+// isConflicted returns true if any edit of the fix overlaps an already collected edit,
+// two insertions at the same position are conflicted too, because their order is unknown
+func (m *Modifier) isConflicted(fix *Fix) bool {
+	for _, edit := range fix.Edits {
+		for _, other := range m.toFix[edit.Filename] {
+			if edit.Start < other.End && other.Start < edit.End ||
+				edit.Start == other.Start && (edit.Start == edit.End || other.Start == other.End) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Conflicts returns the issues, which fixes were not applied because they overlap other fixes,
+// the analyzers should be run again on the modified files to get the updated fixes
+func (m *Modifier) Conflicts() Diagnostic {
+	return m.conflicts
+}
+
+// ReportConflicts moves the conflicted issues, which fixes were not applied, to the diag to be reported
+func (m *Modifier) ReportConflicts(diag Diagnostic) {
+	entries := SortIssues(&m.conflicts, config.DefaultSort, "")
+	if len(entries) == 0 {
+		return
+	}
+	log.Printf("%d issues are not fixed because of the conflicts with other fixes", len(entries))
+	for _, e := range entries {
+		setFixDiffs(e.Issue)
+		m.summary.Add(e.Package, e.Analyzer, e.Issue)
+		diag.addIssue(e.Package, e.Analyzer, e.Issue)
+	}
+	m.conflicts = make(Diagnostic)
+}
+
+// retryKey identifies the conflicted issue after the fixes of other issues changed its position
+func retryKey(analyzer string, issue *Issue) string {
+	filename, _, _ := parsePosN(issue.PosN)
+	return analyzer + "\x00" + filename + "\x00" + issue.Message
+}
+
+// writeFixes applies the collected fixes or writes them as a patch to the file or stdout in dry run mode,
+// the applied fixes are reverted if they break the packages and their issues are added to the diag
+func (m *Modifier) writeFixes(diag Diagnostic) {
+	if !m.conf.DryRun && m.conf.Patch == "" {
+		filenames := sortedKeys(m.toFix)
+		var (
+			before map[string]*PackageErrors
+			err    error
+		)
+		if m.typeCheck != nil {
+			if before, err = m.typeCheck(filenames); err != nil {
+				log.Printf("type-checking the packages to be fixed failed, the fixes are not verified: %+v", err)
+			}
+		}
+		ApplySuggestedFixes(m.toFix)
+		if m.typeCheck != nil && err == nil {
+			m.reportReverted(diag, m.verifyFixes(before, filenames))
+		}
+		return
+	}
+	patch := PatchSuggestedFixes(m.toFix)
+	if m.conf.Patch == "" {
+		if _, err := io.WriteString(os.Stdout, patch); err != nil {
+			log.Printf("writing output failed: %+v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := os.WriteFile(m.conf.Patch, []byte(patch), 0o644); err != nil {
+		log.Fatalf("writing patch file %q failed: %+v", m.conf.Patch, err)
+	}
+}
+
+// reportReverted adds the issues, which fixes were reverted, to the diag to be reported like the skipped fixes
+func (m *Modifier) reportReverted(diag Diagnostic, filenames []string) {
+	if len(filenames) == 0 {
+		return
+	}
+	reverted := make(map[*Entry]struct{})
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		log.Printf("the fixes of file %q break the build and were reverted", filename)
+		for _, e := range m.applied[filename] {
+			if _, ok := reverted[e]; ok {
+				continue
+			}
+			reverted[e] = struct{}{}
+			setFixDiffs(e.Issue)
+			m.summary.Add(e.Package, e.Analyzer, e.Issue)
+			diag.addIssue(e.Package, e.Analyzer, e.Issue)
+		}
+	}
+	m.summary.Fixed -= len(reverted)
+}
+
+// Packages returns the sorted names of all packages passed to the modifier
+func (m *Modifier) Packages() []string {
+	return sortedKeys(m.packages)
+}
+
+// Summary returns the counts of the reported, suppressed and fixed issues, it is complete after Finish only
+func (m *Modifier) Summary() *Summary {
+	return m.summary
+}
+
+func Modify(conf *config.Config, diag *Diagnostic) {
+	m := NewModifier(conf)
+	// the first variant of a package in the order of the names keeps the duplicated issues
+	for _, pkgName := range sortedKeys(*diag) {
+		pkg := (*diag)[pkgName]
+		if !m.Package(pkgName, nil, pkg) {
+			delete(*diag, pkgName)
+		}
+	}
+	m.Finish()
+}
+
+// isExcluded returns true if the issue matches any exclude rule and counts it as suppressed
+// by git_ref if the rule compares the changes
+func (m *Modifier) isExcluded(pkg, analyzer string, issue *Issue) bool {
+	for _, rule := range m.conf.Exclude {
+		if !matchRule(rule, pkg, analyzer, issue) {
+			continue
+		}
+		if rule.GitRef != "" || rule.Patch != "" {
+			m.summary.Suppressed.GitRef++
+		} else {
+			m.summary.Suppressed.Exclude++
+		}
+		return true
 	}
 	return false
 }
 
 func matchRule(rule *config.Rule, pkg, analyzer string, issue *Issue) bool {
-	if rule.Analyzer == "" && rule.PackageRE == nil && rule.PathRE == nil && rule.MessageRE == nil && rule.Severity == "" && rule.GitRef == "" {
+	if rule.Analyzer == "" && rule.PackageRE == nil && rule.PathRE == nil && rule.MessageRE == nil && rule.Severity == "" && rule.GitRef == "" && rule.Patch == "" && rule.Fingerprint == "" {
 		return false
 	}
 	if rule.Analyzer != "" && analyzer != rule.Analyzer {
@@ -100,7 +444,15 @@ func matchRule(rule *config.Rule, pkg, analyzer string, issue *Issue) bool {
 	if rule.Severity != "" && rule.Severity != issue.SeverityLevel {
 		return false
 	}
-	if rule.GitRef != "" {
+	if rule.Fingerprint != "" && rule.Fingerprint != issue.Fingerprint {
+		return false
+	}
+	if rule.Patch != "" || rule.GitLines || rule.GitUncommitted {
+		filename, line, _ := parsePosN(issue.PosN)
+		if getChangedLines(rule).Contains(filename, line) {
+			return false
+		}
+	} else if rule.GitRef != "" {
 		filename, _, _ := parsePosN(issue.PosN)
 		wd, err := os.Getwd()
 		if err != nil {
@@ -143,6 +495,50 @@ func getGitChangedFiles(ref string) map[string]struct{} {
 	return changed
 }
 
+// setFixDiffs calculates the patches of the suggested fixes of the reported issue
+func setFixDiffs(issue *Issue) {
+	for _, fix := range issue.SuggestedFixes {
+		fix.Diff = FixDiff(fix)
+	}
+}
+
+// mergeIssues moves the issues reported at the same position by several analyzers
+// to the sources of the issue of the first analyzer in the order of the names,
+// the issues with the suggested fixes are kept as is, so the fixes of an issue are always the alternatives
+func mergeIssues(pkg map[string]*IssuesOrError) {
+	type owner struct {
+		analyzer string
+		issue    *Issue
+	}
+	first := make(map[string]*owner)
+	for _, name := range sortedKeys(pkg) {
+		obj := pkg[name]
+		if obj.Error != "" {
+			continue
+		}
+		tmp := make([]*Issue, 0, len(obj.Issues))
+		for _, issue := range obj.Issues {
+			o, ok := first[issue.PosN]
+			if !ok {
+				first[issue.PosN] = &owner{analyzer: name, issue: issue}
+			}
+			if !ok || o.analyzer == name || len(issue.SuggestedFixes) > 0 {
+				tmp = append(tmp, issue)
+				continue
+			}
+			o.issue.Sources = append(o.issue.Sources, &Source{Analyzer: name, Message: issue.Message})
+			if severityOrder[issue.SeverityLevel] < severityOrder[o.issue.SeverityLevel] {
+				o.issue.SeverityLevel = issue.SeverityLevel
+			}
+		}
+		if len(tmp) == 0 {
+			delete(pkg, name)
+		} else {
+			obj.Issues = tmp
+		}
+	}
+}
+
 func setSeverityLevel(sevRules []*config.SeverityRule, pkg, analyzer string, issue *Issue) {
 	issue.SeverityLevel = config.ErrorLevel
 	for _, sev := range sevRules {
@@ -155,45 +551,20 @@ func setSeverityLevel(sevRules []*config.SeverityRule, pkg, analyzer string, iss
 	}
 }
 
+// ApplySuggestedFixes writes the files with the given edits applied
 func ApplySuggestedFixes(fixes map[string][]*Edit) {
 	for filename, edits := range fixes {
-		sort.Slice(edits, func(i, j int) bool {
-			return edits[i].Start < edits[j].Start
-		})
 		f, err := getFile(filename)
 		if err != nil {
 			log.Fatalf("reading file %q failed: %+v", filename, err)
 		}
-		var end int64
-		r := bytes.NewReader(f.Data)
-		buf := bytes.Buffer{}
-		for _, edit := range edits {
-			l := int64(edit.Start) - end
-			switch {
-			case l < 0:
-				log.Fatalf("overlapped change for file %q: %#v", filename, edit)
-			case l > 0:
-				b := make([]byte, l)
-				if _, err := r.Read(b); err != nil {
-					log.Fatalf("reading failed: %+v", err)
-				}
-				buf.Write(b)
-			}
-			buf.WriteString(edit.New)
-			if end, err = r.Seek(int64(edit.End), io.SeekStart); err != nil {
-				log.Fatalf("seeking failed: %+v", end)
-			}
-		}
-		b, err := io.ReadAll(r)
+		data, err := applyEdits(f.Data, edits)
 		if err != nil {
-			log.Fatalf("reading leftovers failed: %#v", err)
-		}
-		if len(b) > 0 {
-			buf.Write(b)
+			log.Fatalf("applying changes to file %q failed: %+v", filename, err)
 		}
-		f.Data = buf.Bytes()
-		if err := os.WriteFile(f.Filename, f.Data, 0o644); err != nil {
-			log.Fatalf("writing to file %q failed: %#v", f.Filename, err)
+		// the cached file keeps the analyzed source, so the remaining issues are rendered at their positions
+		if err := writeFileAtomic(filename, data); err != nil {
+			log.Fatalf("writing to file %q failed: %+v", f.Filename, err)
 		}
 	}
 }
@@ -0,0 +1,308 @@
+package output_test
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestModifyDuplicates(t *testing.T) {
+	newDiag := func() output.Diagnostic {
+		return output.Diagnostic{
+			"pkg": {
+				"shadow": {Issues: []*output.Issue{{PosN: "a.go:1:1", Message: "shadowed"}}},
+				"printf": {Issues: []*output.Issue{{PosN: "a.go:1:1", Message: "wrong format"}}},
+				"unusedwrite": {Issues: []*output.Issue{{
+					PosN:           "a.go:1:1",
+					Message:        "unused write",
+					SuggestedFixes: []*output.Fix{{Message: "remove the write"}},
+				}}},
+			},
+			"pkg [pkg.test]": {
+				"shadow": {Issues: []*output.Issue{
+					{PosN: "a.go:1:1", Message: "shadowed"},
+					{PosN: "a_test.go:1:1", Message: "shadowed"},
+				}},
+			},
+		}
+	}
+	for _, tt := range []struct {
+		name     string
+		merge    bool
+		expected map[string]map[string][]string
+	}{
+		{
+			name: "dedup",
+			expected: map[string]map[string][]string{
+				"pkg":            {"shadow": {"a.go:1:1"}, "printf": {"a.go:1:1"}, "unusedwrite": {"a.go:1:1"}},
+				"pkg [pkg.test]": {"shadow": {"a_test.go:1:1"}},
+			},
+		},
+		{
+			name:  "merge",
+			merge: true,
+			expected: map[string]map[string][]string{
+				"pkg":            {"printf": {"a.go:1:1 shadow"}, "unusedwrite": {"a.go:1:1"}},
+				"pkg [pkg.test]": {"shadow": {"a_test.go:1:1"}},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diag := newDiag()
+			output.Modify(&config.Config{Merge: tt.merge}, &diag)
+			actual := make(map[string]map[string][]string)
+			for pkgName, pkg := range diag {
+				actual[pkgName] = make(map[string][]string)
+				for name, obj := range pkg {
+					for _, issue := range obj.Issues {
+						text := issue.PosN
+						for _, src := range issue.Sources {
+							text += " " + src.Analyzer
+						}
+						actual[pkgName][name] = append(actual[pkgName][name], text)
+					}
+				}
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+const fixtureSource = "package a\n\nvar x = 1\n"
+
+// writeFixture writes the go file declaring a variable after the header
+// and returns the issue with the fix to make the variable a constant
+func writeFixture(t *testing.T, filename, header string) *output.Issue {
+	require.NoError(t, os.WriteFile(filename, []byte(header+fixtureSource), 0o600))
+	start := token.Pos(len(header) + 11)
+	return &output.Issue{
+		PosN:    filename + ":3:1",
+		Message: "use const",
+		SuggestedFixes: []*output.Fix{
+			{Message: "replace with const", Edits: []*output.Edit{{Filename: filename, Start: start, End: start + 3, New: "const"}}},
+		},
+	}
+}
+
+// newFixModifier returns the modifier with the given directory as the main module, the files outside are never fixed
+func newFixModifier(conf *config.Config, dir string) *output.Modifier {
+	m := output.NewModifier(conf)
+	m.SetModuleDir(dir)
+	return m
+}
+
+func readFile(t *testing.T, filename string) string {
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestModifierConflicts(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.go")
+	gci := writeFixture(t, filename, "")
+	gofumpt := writeFixture(t, filename, "")
+	gofumpt.SuggestedFixes[0].Edits[0].End = 20
+	gofumpt.SuggestedFixes[0].Edits[0].New = "x := 1"
+
+	m := newFixModifier(&config.Config{Fix: true}, dir)
+	m.Package("a", nil, map[string]*output.IssuesOrError{
+		"gci":     {Issues: []*output.Issue{gci}},
+		"gofumpt": {Issues: []*output.Issue{gofumpt}},
+	})
+	m.Finish()
+
+	require.Equal(t, "package a\n\nconst x = 1\n", readFile(t, filename))
+	conflicts := m.Conflicts()
+	require.Len(t, conflicts["a"], 1)
+	require.Contains(t, conflicts["a"], "gofumpt")
+
+	diag := make(output.Diagnostic)
+	m.ReportConflicts(diag)
+	require.Len(t, diag["a"]["gofumpt"].Issues, 1)
+	require.Empty(t, m.Conflicts())
+	require.Equal(t, 1, m.Summary().Total)
+	require.Equal(t, 1, m.Summary().Fixed)
+}
+
+func TestModifierFixAlternatives(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		alternatives []*config.FixAlternative
+		single       bool
+		expected     string
+	}{
+		{
+			name:     "first by default",
+			expected: "package a\n\nconst x = 1\n",
+		},
+		{
+			name:         "selected by message",
+			alternatives: []*config.FixAlternative{{Analyzer: "test", MessageRE: regexp.MustCompile("^remove")}},
+			expected:     "package a\n\n",
+		},
+		{
+			name:         "other analyzer",
+			alternatives: []*config.FixAlternative{{Analyzer: "other", MessageRE: regexp.MustCompile("^remove")}},
+			expected:     "package a\n\nconst x = 1\n",
+		},
+		{
+			name:         "ambiguous",
+			alternatives: []*config.FixAlternative{{MessageRE: regexp.MustCompile("e")}},
+			expected:     fixtureSource,
+		},
+		{
+			name:         "single fix",
+			alternatives: []*config.FixAlternative{{MessageRE: regexp.MustCompile("^remove")}},
+			single:       true,
+			expected:     "package a\n\nconst x = 1\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "a.go")
+			issue := writeFixture(t, filename, "")
+			if !tt.single {
+				issue.SuggestedFixes = append(issue.SuggestedFixes, &output.Fix{
+					Message: "remove declaration",
+					Edits:   []*output.Edit{{Filename: filename, Start: 11, End: 21, New: ""}},
+				})
+			}
+
+			m := newFixModifier(&config.Config{Fix: true, FixAlternatives: tt.alternatives}, dir)
+			m.Package("a", nil, map[string]*output.IssuesOrError{"test": {Issues: []*output.Issue{issue}}})
+			m.Finish()
+
+			require.Equal(t, tt.expected, readFile(t, filename))
+		})
+	}
+}
+
+func TestModifierSafeFixes(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		outside  bool
+		header   string
+		expected string
+	}{
+		{
+			name:     "fixed",
+			expected: "package a\n\nconst x = 1\n",
+		},
+		{
+			name:     "generated",
+			header:   "// Code generated by test. DO NOT EDIT.\n\n",
+			expected: fixtureSource,
+		},
+		{
+			name:     "outside of module",
+			outside:  true,
+			expected: fixtureSource,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "a.go")
+			issue := writeFixture(t, filename, tt.header)
+			if tt.outside {
+				dir = t.TempDir()
+			}
+
+			m := newFixModifier(&config.Config{Fix: true}, dir)
+			m.Package("a", nil, map[string]*output.IssuesOrError{"test": {Issues: []*output.Issue{issue}}})
+			m.Finish()
+
+			require.Equal(t, tt.header+tt.expected, readFile(t, filename))
+			fi, err := os.Stat(filename)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+		})
+	}
+}
+
+func TestModifierRevertsBrokenFixes(t *testing.T) {
+	dir := t.TempDir()
+	fixed := filepath.Join(dir, "fixed.go")
+	broken := filepath.Join(dir, "broken.go")
+	issues := []*output.Issue{writeFixture(t, fixed, ""), writeFixture(t, broken, "")}
+
+	m := newFixModifier(&config.Config{Fix: true}, dir)
+	m.SetTypeChecker(func(filenames []string) (map[string]*output.PackageErrors, error) {
+		errs := map[string]*output.PackageErrors{
+			"fixed":    {Files: []string{fixed}, Errors: []string{"fixed.go: known error"}},
+			"broken":   {Files: []string{broken}},
+			"importer": {Files: []string{"importer.go"}, Deps: []string{"broken"}},
+		}
+		if readFile(t, broken) != fixtureSource {
+			errs["importer"].Errors = []string{"importer.go: undefined: x"}
+		}
+		return errs, nil
+	})
+	m.Package("a", nil, map[string]*output.IssuesOrError{"test": {Issues: issues}})
+	diag := m.Finish()
+
+	require.Equal(t, "package a\n\nconst x = 1\n", readFile(t, fixed))
+	require.Equal(t, fixtureSource, readFile(t, broken))
+	require.Equal(t, 1, m.Summary().Fixed)
+	require.Equal(t, issues[1:], diag["a"]["test"].Issues)
+}
+
+func TestModifierFixRestrictions(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		fix   config.FixConfig
+		posN  string
+		fixed bool
+	}{
+		{name: "no restrictions", fixed: true},
+		{name: "allowed analyzer", fix: config.FixConfig{Analyzers: []string{"gofumpt", "test"}}, fixed: true},
+		{name: "other analyzer", fix: config.FixConfig{Analyzers: []string{"gofumpt"}}},
+		{name: "allowed severity", fix: config.FixConfig{Severity: []string{config.ErrorLevel}}, fixed: true},
+		{name: "other severity", fix: config.FixConfig{Severity: []string{config.InfoLevel}}},
+		{name: "allowed path", fix: config.FixConfig{PathsRE: []*regexp.Regexp{regexp.MustCompile(`a\.go`)}}, fixed: true},
+		{name: "other path", fix: config.FixConfig{PathsRE: []*regexp.Regexp{regexp.MustCompile(`b\.go`)}}},
+		{name: "issue in allowed path", fix: config.FixConfig{PathsRE: []*regexp.Regexp{regexp.MustCompile(`b\.go`)}}, posN: "b.go:3:1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "a.go")
+			issue := writeFixture(t, filename, "")
+			if tt.posN != "" {
+				issue.PosN = tt.posN
+			}
+
+			m := newFixModifier(&config.Config{Fix: true, FixConfig: tt.fix}, dir)
+			reported := m.Package("a", nil, map[string]*output.IssuesOrError{"test": {Issues: []*output.Issue{issue}}})
+			m.Finish()
+
+			require.Equal(t, !tt.fixed, reported)
+			require.Equal(t, tt.fixed, readFile(t, filename) == "package a\n\nconst x = 1\n")
+		})
+	}
+}
+
+func TestModifierPatch(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.go")
+	issue := writeFixture(t, filename, "")
+	patch := filepath.Join(t.TempDir(), "fixes.diff")
+
+	m := newFixModifier(&config.Config{Fix: true, Patch: patch}, dir)
+	m.Package("a", nil, map[string]*output.IssuesOrError{"test": {Issues: []*output.Issue{issue}}})
+	diag := m.Finish()
+
+	require.Equal(t, fixtureSource, readFile(t, filename))
+	require.Contains(t, readFile(t, patch), "-var x = 1\n+const x = 1\n")
+	require.Equal(t, []*output.Issue{issue}, diag["a"]["test"].Issues)
+	require.NotEmpty(t, issue.SuggestedFixes[0].Diff)
+	require.Equal(t, 1, m.Summary().Total)
+	require.Equal(t, 0, m.Summary().Fixed)
+	require.Equal(t, 1, m.Summary().Patched)
+}
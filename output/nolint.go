@@ -0,0 +1,324 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/sv-tools/gochecker/analyzers"
+	"github.com/sv-tools/gochecker/config"
+)
+
+const (
+	// AllAnalyzers is the name to suppress the issues of all analyzers: `//nolint:all`
+	AllAnalyzers = "all"
+	// NolintName is the name of the built-in check of the nolint directives
+	NolintName = "nolint"
+)
+
+// nolintRE matches the directives in golangci-lint format: `//nolint:analyzer1,analyzer2 // reason`
+var nolintRE = regexp.MustCompile(`^//\s*nolint(?::\s*([\w-]+(?:\s*,\s*[\w-]+)*))?(?:\s*//\s*(.*?))?\s*$`)
+
+// looseNolintRE matches any comment meant to be a directive, the ones not matching nolintRE are malformed
+var looseNolintRE = regexp.MustCompile(`^//\s*nolint\b`)
+
+// NolintDirective is a parsed `//nolint` comment, which suppresses the issues reported in the range of lines
+// from From to To inclusively.
+// Start and End are the offsets of the text to be removed along with the directive.
+// A malformed directive, e.g. `//nolint errcheck`, suppresses nothing.
+type NolintDirective struct {
+	Filename  string
+	Text      string
+	Reason    string
+	Analyzers []string
+	Line      int
+	Column    int
+	From      int
+	To        int
+	Start     int
+	End       int
+	Used      bool
+	Malformed bool
+}
+
+// ParseNolint parses the comment and returns nil if the comment is not a nolint directive,
+// the comments starting with `//nolint` in other format are returned as malformed directives
+func ParseNolint(text string) *NolintDirective {
+	m := nolintRE.FindStringSubmatch(text)
+	if m == nil {
+		if looseNolintRE.MatchString(text) {
+			return &NolintDirective{Text: text, Malformed: true}
+		}
+		return nil
+	}
+	d := NolintDirective{
+		Text:   text,
+		Reason: strings.TrimSpace(m[2]),
+	}
+	if m[1] != "" {
+		for _, name := range strings.Split(m[1], ",") {
+			d.Analyzers = append(d.Analyzers, strings.TrimSpace(name))
+		}
+	}
+	return &d
+}
+
+// Match returns true if the directive suppresses the issues of the given analyzer
+func (d *NolintDirective) Match(analyzer string) bool {
+	if d.Malformed {
+		return false
+	}
+	if len(d.Analyzers) == 0 {
+		return true
+	}
+	for _, name := range d.Analyzers {
+		if name == analyzer || name == AllAnalyzers || name == analyzers.GoVetName && isGoVet(analyzer) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGoVet(analyzer string) bool {
+	for _, list := range [][]*analysis.Analyzer{analyzers.GoVet, analyzers.GoVetExtra} {
+		for _, a := range list {
+			if a.Name == analyzer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nolinter holds the parsed nolint directives by file names
+type nolinter struct {
+	conf  *config.Nolint
+	files map[string][]*NolintDirective
+}
+
+func newNolinter(conf *config.Nolint) *nolinter {
+	return &nolinter{
+		conf:  conf,
+		files: make(map[string][]*NolintDirective),
+	}
+}
+
+// directives parses the file once and returns all its nolint directives
+func (n *nolinter) directives(filename string) []*NolintDirective {
+	if v, ok := n.files[filename]; ok {
+		return v
+	}
+	var directives []*NolintDirective
+	if f, err := getFile(filename); err == nil {
+		directives = ParseNolintDirectives(filename, f.Data)
+	}
+	n.files[filename] = directives
+	return directives
+}
+
+// ParseNolintDirectives parses the source code and returns all nolint directives.
+//
+// A directive at the end of a line suppresses the issues on that line only.
+// A directive on its own line suppresses the issues of the whole declaration or statement that starts
+// on the next line after the comment group of the directive, so a directive in the doc comment of a function
+// suppresses the issues of the whole function and a directive above the package clause suppresses
+// the issues of the whole file.
+func ParseNolintDirectives(filename string, src []byte) []*NolintDirective {
+	fset := token.NewFileSet()
+	// use the comments even if the file cannot be fully parsed
+	file, _ := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	lines := bytes.Split(src, []byte("\n"))
+
+	var (
+		directives []*NolintDirective
+		nodes      map[int]ast.Node
+	)
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			d := ParseNolint(c.Text)
+			if d == nil {
+				continue
+			}
+			pos := fset.Position(c.Pos())
+			d.Filename = filename
+			d.Line = pos.Line
+			d.Column = pos.Column
+			d.From = pos.Line
+			d.To = pos.Line
+			d.Start = pos.Offset
+			d.End = fset.Position(c.End()).Offset
+			directives = append(directives, d)
+			if pos.Line > len(lines) || len(bytes.TrimSpace(lines[pos.Line-1][:pos.Column-1])) > 0 {
+				// the directive at the end of a line, the spaces before it are removed too
+				for d.Start > 0 && (src[d.Start-1] == ' ' || src[d.Start-1] == '\t') {
+					d.Start--
+				}
+				continue
+			}
+			// the directive on its own line, the whole line is removed
+			d.Start -= pos.Column - 1
+			if d.End < len(src) && src[d.End] == '\n' {
+				d.End++
+			}
+			next := fset.Position(group.End()).Line + 1
+			if fset.Position(file.Package).Line == next {
+				d.From = 1
+				d.To = math.MaxInt
+				continue
+			}
+			if nodes == nil {
+				nodes = nodesByLine(fset, file)
+			}
+			if node, ok := nodes[next]; ok {
+				d.To = fset.Position(node.End()).Line
+			}
+		}
+	}
+	return directives
+}
+
+// nodesByLine returns the outermost node starting on each line
+func nodesByLine(fset *token.FileSet, file *ast.File) map[int]ast.Node {
+	nodes := make(map[int]ast.Node)
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node.(type) {
+		case nil, *ast.File, *ast.CommentGroup, *ast.Comment:
+			return true
+		}
+		line := fset.Position(node.Pos()).Line
+		if _, ok := nodes[line]; !ok {
+			nodes[line] = node
+		}
+		return true
+	})
+	return nodes
+}
+
+// allowed returns true if the directive is allowed to suppress the issues of the analyzer by the config
+func (n *nolinter) allowed(d *NolintDirective, analyzer string) bool {
+	if n.conf.RequireReason && d.Reason == "" {
+		return false
+	}
+	if len(n.conf.Allowed) == 0 {
+		return true
+	}
+	for _, name := range n.conf.Allowed {
+		if name == analyzer || name == analyzers.GoVetName && isGoVet(analyzer) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNolint returns true if the issue is suppressed by a nolint directive
+func (n *nolinter) isNolint(analyzer string, issue *Issue) bool {
+	filename, line, column := parsePosN(issue.PosN)
+	if line == -1 {
+		return false
+	}
+	for _, d := range n.directives(filename) {
+		if analyzer == NolintName && d.Line == line && d.Column == column {
+			// the directive cannot suppress the issue reported about itself
+			continue
+		}
+		if d.From <= line && line <= d.To && d.Match(analyzer) && n.allowed(d, analyzer) {
+			d.Used = true
+			return true
+		}
+	}
+	return false
+}
+
+// check reports the directives of the given files, which are malformed, name unknown analyzers, have no required reason
+// or suppressed nothing, the unused directives are reported only if all their analyzers were enabled.
+// The files are mapped to the package names.
+func (n *nolinter) check(files map[string]string, enabled map[string]map[string]string) Diagnostic {
+	known := map[string]struct{}{AllAnalyzers: {}, NolintName: {}, analyzers.GoVetName: {}}
+	for _, a := range analyzers.Analyzers {
+		known[a.Name] = struct{}{}
+	}
+	isEnabled := func(name string) bool {
+		if name == NolintName {
+			// the directives suppressing this check are used after the check only
+			return false
+		}
+		if len(enabled) == 0 {
+			return true
+		}
+		switch name {
+		case AllAnalyzers:
+			return false
+		case analyzers.GoVetName:
+			for a := range enabled {
+				if isGoVet(a) {
+					return true
+				}
+			}
+			return false
+		}
+		_, ok := enabled[name]
+		return ok
+	}
+
+	diag := make(Diagnostic)
+	for filename, pkgName := range files {
+		for _, d := range n.directives(filename) {
+			issue := n.checkDirective(d, known, isEnabled)
+			if issue == nil {
+				continue
+			}
+			diag.addIssue(pkgName, NolintName, issue)
+		}
+	}
+	return diag
+}
+
+func (n *nolinter) checkDirective(d *NolintDirective, known map[string]struct{}, isEnabled func(string) bool) *Issue {
+	issue := Issue{PosN: fmt.Sprintf("%s:%d:%d", d.Filename, d.Line, d.Column)}
+	if d.Malformed {
+		issue.Message = fmt.Sprintf("directive `%s` is malformed, the format is `//nolint:analyzer1,analyzer2 // reason`", d.Text)
+		return &issue
+	}
+	for _, name := range d.Analyzers {
+		if _, ok := known[name]; !ok {
+			issue.Message = fmt.Sprintf("directive `%s` names unknown analyzer %q", d.Text, name)
+			return &issue
+		}
+	}
+	if n.conf.RequireReason && d.Reason == "" {
+		issue.Message = fmt.Sprintf("directive `%s` should provide the reason after `//`", d.Text)
+		return &issue
+	}
+	if d.Used {
+		return nil
+	}
+	names := d.Analyzers
+	if len(names) == 0 {
+		names = []string{AllAnalyzers}
+	}
+	for _, name := range names {
+		if !isEnabled(name) {
+			return nil
+		}
+	}
+	issue.Message = fmt.Sprintf("directive `%s` is unused", d.Text)
+	issue.SuggestedFixes = []*Fix{{
+		Message: "remove the unused directive",
+		Edits: []*Edit{{
+			Filename: d.Filename,
+			Start:    token.Pos(d.Start),
+			End:      token.Pos(d.End),
+		}},
+	}}
+	return &issue
+}
@@ -0,0 +1,235 @@
+package output_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestParseNolint(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		text       string
+		expected   *output.NolintDirective
+		analyzer   string
+		suppressed bool
+	}{
+		{
+			name: "not a directive",
+			text: "// some comment",
+		},
+		{
+			name: "nolintlint",
+			text: "//nolintlint",
+		},
+		{
+			name:     "analyzer without colon",
+			text:     "//nolint errcheck",
+			expected: &output.NolintDirective{Text: "//nolint errcheck", Malformed: true},
+			analyzer: "errcheck",
+		},
+		{
+			name:     "reason without slashes",
+			text:     "//nolint:errcheck reason",
+			expected: &output.NolintDirective{Text: "//nolint:errcheck reason", Malformed: true},
+			analyzer: "errcheck",
+		},
+		{
+			name:     "trailing comma",
+			text:     "//nolint:errcheck,",
+			expected: &output.NolintDirective{Text: "//nolint:errcheck,", Malformed: true},
+			analyzer: "errcheck",
+		},
+		{
+			name:       "all analyzers",
+			text:       "//nolint",
+			expected:   &output.NolintDirective{Text: "//nolint"},
+			analyzer:   "errcheck",
+			suppressed: true,
+		},
+		{
+			name: "analyzers with reason",
+			text: "//nolint:errcheck, gci // the error is always nil",
+			expected: &output.NolintDirective{
+				Text:      "//nolint:errcheck, gci // the error is always nil",
+				Reason:    "the error is always nil",
+				Analyzers: []string{"errcheck", "gci"},
+			},
+			analyzer:   "gci",
+			suppressed: true,
+		},
+		{
+			name: "another analyzer",
+			text: "// nolint:errcheck",
+			expected: &output.NolintDirective{
+				Text:      "// nolint:errcheck",
+				Analyzers: []string{"errcheck"},
+			},
+			analyzer: "gofumpt",
+		},
+		{
+			name: "govet",
+			text: "//nolint:govet // false positive",
+			expected: &output.NolintDirective{
+				Text:      "//nolint:govet // false positive",
+				Reason:    "false positive",
+				Analyzers: []string{"govet"},
+			},
+			analyzer:   "shadow",
+			suppressed: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := output.ParseNolint(tt.text)
+			require.Equal(t, tt.expected, actual)
+			if actual != nil {
+				require.Equal(t, tt.suppressed, actual.Match(tt.analyzer))
+			}
+		})
+	}
+}
+
+const nolintFile = `package pkg
+
+import "os"
+
+// F does nothing
+//
+//nolint:errcheck // the errors are ignored by design
+func F() {
+	os.Remove("a")
+	os.Remove("b")
+}
+
+func G() {
+	//nolint:gofumpt
+	if true {
+		os.Remove("c")
+	}
+	os.Remove("d") //nolint
+}
+`
+
+func TestParseNolintDirectives(t *testing.T) {
+	directives := output.ParseNolintDirectives("pkg.go", []byte(nolintFile))
+	require.Len(t, directives, 3)
+
+	require.Equal(t, []string{"errcheck"}, directives[0].Analyzers)
+	require.Equal(t, 7, directives[0].Line)
+	require.Equal(t, 7, directives[0].From)
+	require.Equal(t, 11, directives[0].To)
+
+	require.Equal(t, []string{"gofumpt"}, directives[1].Analyzers)
+	require.Equal(t, 14, directives[1].From)
+	require.Equal(t, 17, directives[1].To)
+	require.Equal(t, "\t//nolint:gofumpt\n", nolintFile[directives[1].Start:directives[1].End])
+
+	require.Empty(t, directives[2].Analyzers)
+	require.Equal(t, 18, directives[2].From)
+	require.Equal(t, 18, directives[2].To)
+	require.Equal(t, " //nolint", nolintFile[directives[2].Start:directives[2].End])
+
+	fileDirectives := output.ParseNolintDirectives("pkg.go", []byte("//nolint:all\npackage pkg\n"))
+	require.Len(t, fileDirectives, 1)
+	require.Equal(t, 1, fileDirectives[0].From)
+	require.Equal(t, math.MaxInt, fileDirectives[0].To)
+}
+
+func TestNolintCheck(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		directive string
+		conf      config.Nolint
+		enabled   []string
+		expected  string
+		fix       string
+	}{
+		{
+			name:      "unknown analyzer",
+			directive: "//nolint:unknown // reason",
+			expected:  "directive `//nolint:unknown // reason` names unknown analyzer \"unknown\"",
+		},
+		{
+			name:      "missing reason",
+			directive: "//nolint:errcheck",
+			conf:      config.Nolint{RequireReason: true},
+			expected:  "directive `//nolint:errcheck` should provide the reason after `//`",
+		},
+		{
+			name:      "malformed",
+			directive: "//nolint errcheck",
+			expected:  "directive `//nolint errcheck` is malformed, the format is `//nolint:analyzer1,analyzer2 // reason`",
+		},
+		{
+			name:      "unused",
+			directive: "//nolint:errcheck",
+			expected:  "directive `//nolint:errcheck` is unused",
+			fix:       "package a\n\nvar x = 1\n",
+		},
+		{
+			name:      "unused analyzer not enabled",
+			directive: "//nolint:errcheck",
+			enabled:   []string{"shadow"},
+		},
+		{
+			name:      "unused all with all analyzers enabled",
+			directive: "//nolint:all",
+			expected:  "directive `//nolint:all` is unused",
+			fix:       "package a\n\nvar x = 1\n",
+		},
+		{
+			name:      "unused all with some analyzers enabled",
+			directive: "//nolint:all",
+			enabled:   []string{"errcheck"},
+		},
+		{
+			name:      "unused govet with go vet pass enabled",
+			directive: "//nolint:govet",
+			enabled:   []string{"shadow"},
+			expected:  "directive `//nolint:govet` is unused",
+			fix:       "package a\n\nvar x = 1\n",
+		},
+		{
+			name:      "unused govet without go vet passes",
+			directive: "//nolint:govet",
+			enabled:   []string{"errcheck"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "a.go")
+			src := "package a\n\n" + tt.directive + "\nvar x = 1\n"
+			require.NoError(t, os.WriteFile(filename, []byte(src), 0o600))
+			conf := config.Config{Nolint: tt.conf, Analyzers: make(map[string]map[string]string)}
+			conf.Nolint.Check = true
+			for _, name := range tt.enabled {
+				conf.Analyzers[name] = nil
+			}
+
+			m := output.NewModifier(&conf)
+			m.Package("a", []string{filename}, map[string]*output.IssuesOrError{})
+			diag := m.Finish()
+
+			if tt.expected == "" {
+				require.Empty(t, diag)
+				return
+			}
+			issues := diag["a"][output.NolintName].Issues
+			require.Len(t, issues, 1)
+			require.Equal(t, filename+":3:1", issues[0].PosN)
+			require.Equal(t, tt.expected, issues[0].Message)
+			if tt.fix == "" {
+				require.Empty(t, issues[0].SuggestedFixes)
+				return
+			}
+			require.Len(t, issues[0].SuggestedFixes, 1)
+			edit := issues[0].SuggestedFixes[0].Edits[0]
+			require.Equal(t, tt.fix, src[:edit.Start]+edit.New+src[edit.End:])
+		})
+	}
+}
@@ -4,14 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"go/token"
-	"io"
-	"log"
 
-	"github.com/sv-tools/gochecker/config"
+	"golang.org/x/tools/go/analysis"
 )
 
 type (
-	// Diagnostic is a structure to hold the json output from multichecker
+	// Diagnostic is a structure to hold the issues reported by the analyzers grouped by packages
 	//
 	// JSON example:
 	// ```json
@@ -19,10 +17,26 @@ type (
 	//	 "<package>": {
 	//	   "<analyzer>": {
 	//	     "posn": "</path/to/file.go>:<line num>:<column num>",
+	//	     "end": "</path/to/file.go>:<line num>:<column num>",
 	//	     "message": "<message>",
+	//	     "url": "<documentation url>",
+	//	     "fingerprint": "<hash independent of the line numbers>",
+	//	     "related": [
+	//	       {
+	//	         "message": "<message>",
+	//	         "posn": "</path/to/file.go>:<line num>:<column num>"
+	//	       }
+	//	     ],
+	//	     "sources": [
+	//	       {
+	//	         "analyzer": "<analyzer>",
+	//	         "message": "<message>"
+	//	       }
+	//	     ],
 	//	     "suggested_fixes": [
 	//	       {
 	//	         "message": "",
+	//	         "diff": "<unified patch>",
 	//	         "edits": [
 	//	           {
 	//	             "filename": "</path/to/file.go>",
@@ -43,15 +57,31 @@ type (
 		Issues []*Issue
 	}
 	Issue struct {
-		Message        string `json:"message"`
-		Category       string `json:"category,omitempty"`
-		PosN           string `json:"posn"`
-		SeverityLevel  string `json:"severity_level"`
-		SuggestedFixes []*Fix `json:"suggested_fixes,omitempty"`
+		Message        string     `json:"message"`
+		Category       string     `json:"category,omitempty"`
+		PosN           string     `json:"posn"`
+		End            string     `json:"end,omitempty"`
+		URL            string     `json:"url,omitempty"`
+		Fingerprint    string     `json:"fingerprint,omitempty"`
+		SeverityLevel  string     `json:"severity_level"`
+		Related        []*Related `json:"related,omitempty"`
+		Sources        []*Source  `json:"sources,omitempty"`
+		SuggestedFixes []*Fix     `json:"suggested_fixes,omitempty"`
+	}
+	// Related is a location related to the issue, e.g. the previous declaration of a variable
+	Related struct {
+		Message string `json:"message"`
+		PosN    string `json:"posn"`
+		End     string `json:"end,omitempty"`
+	}
+	// Source is an issue reported at the same position by another analyzer and merged into the issue
+	Source struct {
+		Analyzer string `json:"analyzer"`
+		Message  string `json:"message"`
 	}
 	Fix struct {
 		Message string  `json:"message,omitempty"`
-		Diff    string  `json:"-"` // system field to contain calculated diff
+		Diff    string  `json:"diff,omitempty"` // unified patch of the fix, calculated for the reported issues
 		Edits   []*Edit `json:"edits"`
 	}
 	Edit struct {
@@ -62,21 +92,20 @@ type (
 	}
 )
 
+// UnmarshalJSON decodes either an object with the error or a list of the issues,
+// the unknown fields of the issues are ignored
 func (o *IssuesOrError) UnmarshalJSON(data []byte) error {
-	var e struct {
-		Error string `json:"error"`
-	}
-	r := bytes.NewReader(data)
-	d := json.NewDecoder(r)
-	d.DisallowUnknownFields()
-	if d.Decode(&e) == nil {
+	if b := bytes.TrimSpace(data); len(b) > 0 && b[0] == '{' {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(b, &e); err != nil {
+			return err
+		}
 		o.Error = e.Error
 		return nil
 	}
-	if _, err := r.Seek(0, io.SeekStart); err != nil {
-		return err
-	}
-	return d.Decode(&o.Issues)
+	return json.Unmarshal(data, &o.Issues)
 }
 
 func (o *IssuesOrError) MarshalJSON() ([]byte, error) {
@@ -89,13 +118,86 @@ func (o *IssuesOrError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(o.Issues)
 }
 
-func ParseOutput(conf *config.Config, data []byte) *Diagnostic {
-	out := make(Diagnostic)
-	d := json.NewDecoder(bytes.NewReader(data))
-	d.DisallowUnknownFields()
-	if err := d.Decode(&out); err != nil {
-		log.Fatalf("unmarshaling failed \"%+v\" for response:\n%s", err, string(data))
-	}
-	Modify(conf, &out)
-	return &out
+// Add converts the diagnostics of an analyzer for a package and stores them as issues or an error
+func (d Diagnostic) Add(fset *token.FileSet, pkg, analyzer string, diags []analysis.Diagnostic, err error) {
+	var obj *IssuesOrError
+	switch {
+	case err != nil:
+		obj = &IssuesOrError{Error: err.Error()}
+	case len(diags) > 0:
+		obj = &IssuesOrError{Issues: make([]*Issue, 0, len(diags))}
+		for i := range diags {
+			obj.Issues = append(obj.Issues, NewIssue(fset, &diags[i]))
+		}
+	default:
+		return
+	}
+	p, ok := d[pkg]
+	if !ok {
+		p = make(map[string]*IssuesOrError)
+		d[pkg] = p
+	}
+	p[analyzer] = obj
+}
+
+// addIssue appends the issue to the issues of the analyzer for the package
+func (d Diagnostic) addIssue(pkg, analyzer string, issue *Issue) {
+	p, ok := d[pkg]
+	if !ok {
+		p = make(map[string]*IssuesOrError)
+		d[pkg] = p
+	}
+	obj, ok := p[analyzer]
+	if !ok {
+		obj = &IssuesOrError{}
+		p[analyzer] = obj
+	}
+	obj.Issues = append(obj.Issues, issue)
+}
+
+// NewIssue converts the diagnostic reported by an analyzer into the issue
+func NewIssue(fset *token.FileSet, diag *analysis.Diagnostic) *Issue {
+	issue := Issue{
+		Message:  diag.Message,
+		Category: diag.Category,
+		PosN:     fset.Position(diag.Pos).String(),
+	}
+	issue.End = endPosN(fset, diag.Pos, diag.End)
+	issue.URL = diag.URL
+	for _, rel := range diag.Related {
+		issue.Related = append(issue.Related, &Related{
+			Message: rel.Message,
+			PosN:    fset.Position(rel.Pos).String(),
+			End:     endPosN(fset, rel.Pos, rel.End),
+		})
+	}
+	for _, fix := range diag.SuggestedFixes {
+		f := Fix{
+			Message: fix.Message,
+			Edits:   make([]*Edit, 0, len(fix.TextEdits)),
+		}
+		for _, edit := range fix.TextEdits {
+			start := fset.Position(edit.Pos)
+			end := start
+			if edit.End.IsValid() {
+				end = fset.Position(edit.End)
+			}
+			f.Edits = append(f.Edits, &Edit{
+				Filename: start.Filename,
+				New:      string(edit.NewText),
+				Start:    token.Pos(start.Offset),
+				End:      token.Pos(end.Offset),
+			})
+		}
+		issue.SuggestedFixes = append(issue.SuggestedFixes, &f)
+	}
+	return &issue
+}
+
+// endPosN returns the end position if it is known and differs from the start position
+func endPosN(fset *token.FileSet, pos, end token.Pos) string {
+	if !end.IsValid() || end <= pos {
+		return ""
+	}
+	return fset.Position(end).String()
 }
@@ -0,0 +1,42 @@
+package output_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestIssuesOrErrorUnmarshalJSON(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		data     string
+		expected *output.IssuesOrError
+	}{
+		{
+			name:     "error",
+			data:     `{"error": "failed"}`,
+			expected: &output.IssuesOrError{Error: "failed"},
+		},
+		{
+			name: "issues with related information and unknown fields",
+			data: `[{"posn": "a.go:1:2", "end": "a.go:1:5", "message": "msg", "url": "https://example.com",
+				"related": [{"posn": "a.go:3:1", "message": "declared here"}], "unknown": true}]`,
+			expected: &output.IssuesOrError{Issues: []*output.Issue{{
+				PosN:    "a.go:1:2",
+				End:     "a.go:1:5",
+				Message: "msg",
+				URL:     "https://example.com",
+				Related: []*output.Related{{PosN: "a.go:3:1", Message: "declared here"}},
+			}}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual output.IssuesOrError
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &actual))
+			require.Equal(t, tt.expected, &actual)
+		})
+	}
+}
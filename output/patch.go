@@ -0,0 +1,114 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// patchContext is the number of the unchanged lines around the changes in a patch
+const patchContext = 3
+
+// applyEdits returns the data with the edits applied in the order of their positions,
+// the overlapped edits are not allowed
+func applyEdits(data []byte, edits []*Edit) ([]byte, error) {
+	edits = append([]*Edit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	buf := bytes.Buffer{}
+	cur := 0
+	for _, edit := range edits {
+		start, end := int(edit.Start), int(edit.End)
+		if end < start {
+			end = start
+		}
+		switch {
+		case start < cur:
+			return nil, fmt.Errorf("overlapped change at offset %d", start)
+		case end > len(data):
+			return nil, fmt.Errorf("change at offset %d is out of the file of %d bytes", end, len(data))
+		}
+		buf.Write(data[cur:start])
+		buf.WriteString(edit.New)
+		cur = end
+	}
+	buf.Write(data[cur:])
+	return buf.Bytes(), nil
+}
+
+// PatchSuggestedFixes returns a unified patch of all given edits, which can be applied by `git apply`
+func PatchSuggestedFixes(fixes map[string][]*Edit) string {
+	buf := strings.Builder{}
+	for _, filename := range sortedKeys(fixes) {
+		patch, err := patchFile(filename, fixes[filename])
+		if err != nil {
+			log.Fatalf("creating patch for file %q failed: %+v", filename, err)
+		}
+		buf.WriteString(patch)
+	}
+	return buf.String()
+}
+
+// FixDiff returns a unified patch of the suggested fix or an empty string if the fix cannot be applied
+func FixDiff(fix *Fix) string {
+	edits := make(map[string][]*Edit)
+	for _, edit := range fix.Edits {
+		edits[edit.Filename] = append(edits[edit.Filename], edit)
+	}
+	buf := strings.Builder{}
+	for _, filename := range sortedKeys(edits) {
+		patch, err := patchFile(filename, edits[filename])
+		if err != nil {
+			log.Printf("creating patch for file %q failed: %+v", filename, err)
+			return ""
+		}
+		buf.WriteString(patch)
+	}
+	return buf.String()
+}
+
+// patchFile returns the git style unified diff of the file with the edits applied,
+// the file names are relative to the working directory
+func patchFile(filename string, edits []*Edit) (string, error) {
+	f, err := getFile(filename)
+	if err != nil {
+		return "", err
+	}
+	fixed, err := applyEdits(f.Data, edits)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.ToSlash(f.Filename)
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(f.Data),
+		B:        splitLines(fixed),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  patchContext,
+	})
+	if err != nil || diff == "" {
+		return "", err
+	}
+	return "diff --git a/" + name + " b/" + name + "\n" + diff, nil
+}
+
+// noNewlineMarker follows the last line of a file without the newline at the end in a patch
+const noNewlineMarker = "\n\\ No newline at end of file\n"
+
+// splitLines splits the data into the lines keeping the line endings,
+// unlike difflib.SplitLines it does not add an empty line at the end.
+// The last line without the newline is followed by the marker, so it differs from the same line with the newline.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += noNewlineMarker
+	return lines
+}
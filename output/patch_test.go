@@ -0,0 +1,56 @@
+package output_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestPatchSuggestedFixes(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		edits []*output.Edit
+		hunk  string
+	}{
+		{
+			name: "newline at end of file",
+			data: "package a\n\nvar x = 1 // comment\n",
+			edits: []*output.Edit{
+				{Start: 20, End: 31, New: ""},
+				{Start: 10, End: 10, New: "// x\n"},
+			},
+			hunk: "@@ -1,3 +1,4 @@\n package a\n+// x\n \n-var x = 1 // comment\n+var x = 1\n",
+		},
+		{
+			name:  "no newline at end of file",
+			data:  "package a\n\nvar x = 1",
+			edits: []*output.Edit{{Start: 11, End: 14, New: "const"}},
+			hunk: "@@ -1,3 +1,3 @@\n package a\n \n-var x = 1\n\\ No newline at end of file\n" +
+				"+const x = 1\n\\ No newline at end of file\n",
+		},
+		{
+			name:  "newline added at end of file",
+			data:  "package a\n\nvar x = 1",
+			edits: []*output.Edit{{Start: 20, End: 20, New: "\n"}},
+			hunk:  "@@ -1,3 +1,3 @@\n package a\n \n-var x = 1\n\\ No newline at end of file\n+var x = 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "a.go")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.data), 0o600))
+			for _, edit := range tt.edits {
+				edit.Filename = filename
+			}
+
+			patch := output.PatchSuggestedFixes(map[string][]*output.Edit{filename: tt.edits})
+			require.Regexp(t, `^diff --git a/\S+a\.go b/\S+a\.go\n--- a/\S+a\.go\n\+\+\+ b/\S+a\.go\n`, patch)
+			require.Contains(t, patch, tt.hunk)
+		})
+	}
+}
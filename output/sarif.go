@@ -0,0 +1,279 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sv-tools/gochecker/analyzers"
+	"github.com/sv-tools/gochecker/config"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifSrcRoot = "%SRCROOT%"
+	// sarifFingerprint is the versioned name of the fingerprint, it must be changed along with the algorithm
+	sarifFingerprint = "gochecker/v1"
+)
+
+// the subset of SARIF 2.1.0 used to report the issues
+type (
+	sarifLog struct {
+		Version string      `json:"version"`
+		Schema  string      `json:"$schema"`
+		Runs    []*sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool        sarifTool          `json:"tool"`
+		Invocations []*sarifInvocation `json:"invocations"`
+		Results     []*sarifResult     `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string       `json:"name"`
+		InformationURI string       `json:"informationUri"`
+		Rules          []*sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		Name                 string             `json:"name"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		FullDescription      sarifMessage       `json:"fullDescription"`
+		HelpURI              string             `json:"helpUri,omitempty"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	}
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifInvocation struct {
+		ExecutionSuccessful        bool                 `json:"executionSuccessful"`
+		ToolExecutionNotifications []*sarifNotification `json:"toolExecutionNotifications,omitempty"`
+	}
+	sarifNotification struct {
+		Level   string       `json:"level"`
+		Message sarifMessage `json:"message"`
+	}
+	sarifResult struct {
+		RuleID              string            `json:"ruleId"`
+		RuleIndex           int               `json:"ruleIndex"`
+		Level               string            `json:"level"`
+		Message             sarifMessage      `json:"message"`
+		Locations           []*sarifLocation  `json:"locations,omitempty"`
+		RelatedLocations    []*sarifLocation  `json:"relatedLocations,omitempty"`
+		Fixes               []*sarifFix       `json:"fixes,omitempty"`
+		PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+		Properties          map[string]string `json:"properties,omitempty"`
+	}
+	sarifLocation struct {
+		ID               *int                  `json:"id,omitempty"`
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+	sarifFix struct {
+		Description     sarifMessage           `json:"description"`
+		ArtifactChanges []*sarifArtifactChange `json:"artifactChanges"`
+	}
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Replacements     []*sarifReplacement   `json:"replacements"`
+	}
+	sarifReplacement struct {
+		DeletedRegion   sarifByteRegion `json:"deletedRegion"`
+		InsertedContent sarifMessage    `json:"insertedContent"`
+	}
+	sarifByteRegion struct {
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+	}
+)
+
+// PrintAsSARIF prints all issues as a single SARIF 2.1.0 document, each analyzer is a rule
+func PrintAsSARIF(w io.Writer, diag *Diagnostic) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gochecker",
+			InformationURI: "https://github.com/sv-tools/gochecker",
+		}},
+		Results: []*sarifResult{},
+	}
+	rules := make(map[string]int)
+	addRule := func(name, doc, url string) int {
+		if idx, ok := rules[name]; ok {
+			return idx
+		}
+		short, _, _ := strings.Cut(doc, "\n\n")
+		rules[name] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+			ID:                   name,
+			Name:                 name,
+			ShortDescription:     sarifMessage{Text: strings.Join(strings.Fields(short), " ")},
+			FullDescription:      sarifMessage{Text: doc},
+			HelpURI:              url,
+			DefaultConfiguration: sarifConfiguration{Level: "error"},
+		})
+		return rules[name]
+	}
+	for _, a := range analyzers.Analyzers {
+		addRule(a.Name, a.Doc, a.URL)
+	}
+	addRule(NolintName, "reports the unused and malformed nolint directives", "")
+
+	invocation := sarifInvocation{ExecutionSuccessful: true}
+	for _, pkgName := range sortedKeys(*diag) {
+		pkg := (*diag)[pkgName]
+		for _, name := range sortedKeys(pkg) {
+			obj := pkg[name]
+			if obj.Error != "" {
+				invocation.ExecutionSuccessful = false
+				invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, &sarifNotification{
+					Level:   "error",
+					Message: sarifMessage{Text: pkgName + ": " + name + ": " + obj.Error},
+				})
+				continue
+			}
+			for _, issue := range obj.Issues {
+				idx := addRule(name, "", "")
+				run.Results = append(run.Results, newSARIFResult(name, idx, issue))
+			}
+		}
+	}
+	run.Invocations = []*sarifInvocation{&invocation}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(&sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []*sarifRun{&run}}); err != nil {
+		log.Fatalf("sarif ouput failed: %+v", err)
+	}
+}
+
+func newSARIFResult(analyzer string, ruleIndex int, issue *Issue) *sarifResult {
+	result := sarifResult{
+		RuleID:    analyzer,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(issue.SeverityLevel),
+		Message:   sarifMessage{Text: issue.Message},
+	}
+	if issue.Category != "" {
+		result.Message.Text = issue.Category + ": " + issue.Message
+	}
+	if issue.Fingerprint != "" {
+		result.PartialFingerprints = map[string]string{sarifFingerprint: issue.Fingerprint}
+	}
+	// the url of the issue may differ from the documentation of the analyzer, so it is kept with the result
+	if issue.URL != "" {
+		result.Properties = map[string]string{"helpUri": issue.URL}
+	}
+	if loc := newSARIFLocation(issue.PosN, issue.End); loc != nil {
+		result.Locations = []*sarifLocation{loc}
+	}
+	for i, rel := range issue.Related {
+		loc := newSARIFLocation(rel.PosN, rel.End)
+		if loc == nil {
+			continue
+		}
+		id := i + 1
+		loc.ID = &id
+		loc.Message = &sarifMessage{Text: rel.Message}
+		result.RelatedLocations = append(result.RelatedLocations, loc)
+	}
+	for _, fix := range issue.SuggestedFixes {
+		f := sarifFix{Description: sarifMessage{Text: fix.Message}}
+		changes := make(map[string]*sarifArtifactChange)
+		for _, edit := range fix.Edits {
+			change, ok := changes[edit.Filename]
+			if !ok {
+				change = &sarifArtifactChange{ArtifactLocation: sarifArtifact(edit.Filename)}
+				changes[edit.Filename] = change
+				f.ArtifactChanges = append(f.ArtifactChanges, change)
+			}
+			length := int(edit.End - edit.Start)
+			if length < 0 {
+				length = 0
+			}
+			change.Replacements = append(change.Replacements, &sarifReplacement{
+				DeletedRegion:   sarifByteRegion{ByteOffset: int(edit.Start), ByteLength: length},
+				InsertedContent: sarifMessage{Text: edit.New},
+			})
+		}
+		result.Fixes = append(result.Fixes, &f)
+	}
+	return &result
+}
+
+// newSARIFLocation returns the location of the range from the start position to the end position if it is known
+func newSARIFLocation(posN, end string) *sarifLocation {
+	filename, line, pos := parsePosN(posN)
+	if filename == "" {
+		return nil
+	}
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifact(filename)}}
+	if line > 0 {
+		region := sarifRegion{StartLine: line}
+		if pos > 0 {
+			region.StartColumn = pos
+		}
+		if endFilename, endLine, endPos := parsePosN(end); end != "" && endFilename == filename && endLine >= line {
+			region.EndLine = endLine
+			if endPos > 0 {
+				region.EndColumn = endPos
+			}
+		}
+		loc.PhysicalLocation.Region = &region
+	}
+	return &loc
+}
+
+// sarifArtifact returns the location of the file relative to the root of the repository, which is the source root,
+// or the absolute location of the file outside of the repository
+func sarifArtifact(filename string) sarifArtifactLocation {
+	if path, ok := repoPath(filename); ok {
+		return sarifArtifactLocation{URI: path, URIBaseID: sarifSrcRoot}
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	return sarifArtifactLocation{URI: "file://" + filepath.ToSlash(filename)}
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case config.WarningLevel:
+		return "warning"
+	case config.InfoLevel:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
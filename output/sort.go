@@ -0,0 +1,110 @@
+package output
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+// Entry is an issue or an error of an analyzer along with the package and the analyzer names
+type Entry struct {
+	Package  string
+	Analyzer string
+	Error    string
+	Issue    *Issue
+	Filename string
+	Line     int
+	Column   int
+}
+
+// Key returns the value of the entry for the given grouping
+func (e *Entry) Key(group string) string {
+	switch group {
+	case config.FileKey:
+		if e.Error != "" {
+			return e.Package
+		}
+		return e.Filename
+	case config.AnalyzerKey:
+		return e.Analyzer
+	case config.PackageKey:
+		return e.Package
+	}
+	return ""
+}
+
+var severityOrder = map[string]int{
+	config.ErrorLevel:   0,
+	config.WarningLevel: 1,
+	config.InfoLevel:    2,
+}
+
+// SortIssues returns the issues of all packages sorted by the group and then by the given keys,
+// the errors of the analyzers go first
+func SortIssues(diag *Diagnostic, keys []string, group string) []*Entry {
+	var entries []*Entry
+	for _, pkgName := range sortedKeys(*diag) {
+		pkg := (*diag)[pkgName]
+		for _, name := range sortedKeys(pkg) {
+			obj := pkg[name]
+			if obj.Error != "" {
+				entries = append(entries, &Entry{Package: pkgName, Analyzer: name, Error: obj.Error})
+				continue
+			}
+			for _, issue := range obj.Issues {
+				filename, line, pos := parsePosN(issue.PosN)
+				entries = append(entries, &Entry{
+					Package:  pkgName,
+					Analyzer: name,
+					Issue:    issue,
+					Filename: filename,
+					Line:     line,
+					Column:   pos,
+				})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		x, y := entries[i], entries[j]
+		if (x.Error != "") != (y.Error != "") {
+			return x.Error != ""
+		}
+		if group != "" && x.Key(group) != y.Key(group) {
+			return x.Key(group) < y.Key(group)
+		}
+		for _, key := range keys {
+			if c := compareEntries(x, y, key); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return entries
+}
+
+func compareEntries(x, y *Entry, key string) int {
+	switch key {
+	case config.FileKey:
+		return strings.Compare(x.Filename, y.Filename)
+	case config.LineKey:
+		return x.Line - y.Line
+	case config.ColumnKey:
+		return x.Column - y.Column
+	case config.AnalyzerKey:
+		return strings.Compare(x.Analyzer, y.Analyzer)
+	case config.PackageKey:
+		return strings.Compare(x.Package, y.Package)
+	case config.SeverityKey:
+		if x.Issue == nil || y.Issue == nil {
+			return 0
+		}
+		return severityOrder[x.Issue.SeverityLevel] - severityOrder[y.Issue.SeverityLevel]
+	case config.MessageKey:
+		if x.Issue == nil || y.Issue == nil {
+			return strings.Compare(x.Error, y.Error)
+		}
+		return strings.Compare(x.Issue.Message, y.Issue.Message)
+	}
+	return 0
+}
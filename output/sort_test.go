@@ -0,0 +1,61 @@
+package output_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+func TestSortIssues(t *testing.T) {
+	diag := output.Diagnostic{
+		"pkg": {
+			"shadow": {Issues: []*output.Issue{
+				{PosN: "b.go:3:1", SeverityLevel: config.WarningLevel},
+				{PosN: "a.go:10:2", SeverityLevel: config.ErrorLevel},
+			}},
+			"printf": {Issues: []*output.Issue{
+				{PosN: "a.go:10:1", SeverityLevel: config.ErrorLevel},
+				{PosN: "b.go:1:1", SeverityLevel: config.InfoLevel},
+			}},
+			"errcheck": {Error: "failed"},
+		},
+	}
+	for _, tt := range []struct {
+		name     string
+		keys     []string
+		group    string
+		expected []string
+	}{
+		{
+			name:     "default",
+			keys:     config.DefaultSort,
+			expected: []string{"errcheck", "printf a.go:10:1", "shadow a.go:10:2", "printf b.go:1:1", "shadow b.go:3:1"},
+		},
+		{
+			name:     "by severity",
+			keys:     []string{config.SeverityKey, config.FileKey},
+			expected: []string{"errcheck", "printf a.go:10:1", "shadow a.go:10:2", "shadow b.go:3:1", "printf b.go:1:1"},
+		},
+		{
+			name:     "grouped by analyzer",
+			keys:     config.DefaultSort,
+			group:    config.AnalyzerKey,
+			expected: []string{"errcheck", "printf a.go:10:1", "printf b.go:1:1", "shadow a.go:10:2", "shadow b.go:3:1"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual []string
+			for _, e := range output.SortIssues(&diag, tt.keys, tt.group) {
+				if e.Issue == nil {
+					actual = append(actual, e.Analyzer)
+					continue
+				}
+				actual = append(actual, e.Analyzer+" "+e.Issue.PosN)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
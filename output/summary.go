@@ -0,0 +1,141 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+// summaryTop is the number of the packages and the files with the most issues printed to console
+const summaryTop = 10
+
+// Summary holds the counts of the reported, suppressed and fixed issues,
+// the issues with the fixes written to the patch instead of the files are reported and counted as patched
+type Summary struct {
+	Total      int            `json:"total"`
+	Severities map[string]int `json:"severities"`
+	Analyzers  map[string]int `json:"analyzers"`
+	Packages   map[string]int `json:"packages"`
+	Files      map[string]int `json:"files"`
+	Suppressed Suppressed     `json:"suppressed"`
+	Fixed      int            `json:"fixed"`
+	Patched    int            `json:"patched"`
+}
+
+// Suppressed holds the counts of the issues suppressed by the nolint directives, the exclude rules,
+// the exclude rules with git_ref or patch, the baseline and the duplicates reported for several variants of a package
+type Suppressed struct {
+	Nolint    int `json:"nolint"`
+	Exclude   int `json:"exclude"`
+	GitRef    int `json:"git_ref"`
+	Baseline  int `json:"baseline"`
+	Duplicate int `json:"duplicate"`
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		Severities: make(map[string]int),
+		Analyzers:  make(map[string]int),
+		Packages:   make(map[string]int),
+		Files:      make(map[string]int),
+	}
+}
+
+// Add counts the reported issue
+func (s *Summary) Add(pkg, analyzer string, issue *Issue) {
+	s.Total++
+	s.Severities[issue.SeverityLevel]++
+	s.Analyzers[analyzer]++
+	s.Packages[pkg]++
+	filename, _, _ := parsePosN(issue.PosN)
+	if f, err := getFile(filename); err == nil {
+		filename = f.Filename
+	}
+	s.Files[filename]++
+}
+
+// PrintSummary prints the summary as a table with the counts by severities and analyzers
+// and the packages and the files with the most issues
+func PrintSummary(w io.Writer, s *Summary) {
+	tw := tabwriter.NewWriter(w, 16, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Summary:\t%d issues\n", s.Total)
+	for _, level := range []string{config.ErrorLevel, config.WarningLevel, config.InfoLevel} {
+		fmt.Fprintf(tw, "  %s\t%d\n", level, s.Severities[level])
+	}
+	for _, section := range []struct {
+		name   string
+		counts map[string]int
+		limit  int
+	}{
+		{name: "Analyzers", counts: s.Analyzers},
+		{name: "Packages", counts: s.Packages, limit: summaryTop},
+		{name: "Files", counts: s.Files, limit: summaryTop},
+	} {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s:\n", section.name)
+		for i, name := range sortedByCount(section.counts) {
+			if section.limit > 0 && i == section.limit {
+				fmt.Fprintf(tw, "  ...\n")
+				break
+			}
+			fmt.Fprintf(tw, "  %s\t%d\n", name, section.counts[name])
+		}
+	}
+	fmt.Fprintf(tw, "Suppressed:\n")
+	fmt.Fprintf(tw, "  nolint\t%d\n", s.Suppressed.Nolint)
+	fmt.Fprintf(tw, "  exclude\t%d\n", s.Suppressed.Exclude)
+	fmt.Fprintf(tw, "  git_ref\t%d\n", s.Suppressed.GitRef)
+	fmt.Fprintf(tw, "  baseline\t%d\n", s.Suppressed.Baseline)
+	fmt.Fprintf(tw, "  duplicate\t%d\n", s.Suppressed.Duplicate)
+	fmt.Fprintf(tw, "Fixed:\t%d\n", s.Fixed)
+	if s.Patched > 0 {
+		fmt.Fprintf(tw, "Patched:\t%d\n", s.Patched)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+}
+
+// PrintSummaryAsGithub prints the summary in a collapsible section
+func PrintSummaryAsGithub(w io.Writer, s *Summary) {
+	if _, err := io.WriteString(w, "::group::summary\n"); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+	PrintSummary(w, s)
+	if _, err := io.WriteString(w, "::endgroup::\n"); err != nil {
+		log.Printf("writing output failed: %+v", err)
+		os.Exit(1)
+	}
+}
+
+// PrintAsJSONWithSummary prints all issues and the summary as a single indented json document:
+// `{"issues": {...}, "summary": {...}}`
+func PrintAsJSONWithSummary(w io.Writer, diag *Diagnostic, s *Summary) {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(struct {
+		Issues  *Diagnostic `json:"issues"`
+		Summary *Summary    `json:"summary"`
+	}{Issues: diag, Summary: s}); err != nil {
+		log.Fatalf("json ouput failed: %+v", err)
+	}
+}
+
+// sortedByCount returns the names sorted by the counts in descending order and then by the names
+func sortedByCount(counts map[string]int) []string {
+	names := sortedKeys(counts)
+	sort.SliceStable(names, func(i, j int) bool {
+		return counts[names[i]] > counts[names[j]]
+	})
+	return names
+}
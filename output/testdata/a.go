@@ -0,0 +1,13 @@
+package a
+
+import "fmt"
+
+func F(s string) {
+	x := 1
+	if true {
+		x := 2
+		fmt.Printf("%d\n", s)
+		_ = x
+	}
+	_ = x
+}
@@ -0,0 +1,150 @@
+package output
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sv-tools/gochecker/config"
+)
+
+// TypeChecker type-checks the packages containing the given files and returns the errors of each package
+type TypeChecker func(filenames []string) (map[string]*PackageErrors, error)
+
+// PackageErrors holds the go files of a package, the IDs of the type-checked packages it depends on
+// and its loading and typing errors with the file names, but without the lines, which are shifted by the fixes
+type PackageErrors struct {
+	Files  []string
+	Deps   []string
+	Errors []string
+}
+
+// writeFileAtomic replaces the file by a temporary file in the same directory, so the file is either
+// fully written or not changed at all, the permissions of the file are preserved
+func writeFileAtomic(filename string, data []byte) (err error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// unsafeFix returns the reason why the fix must not be applied:
+// the generated files and the files outside of the main module are never changed
+func (m *Modifier) unsafeFix(fix *Fix) string {
+	for _, edit := range fix.Edits {
+		f, err := getFile(edit.Filename)
+		if err != nil {
+			return err.Error()
+		}
+		if isGenerated(edit.Filename, f.Data) {
+			return "file " + f.Filename + " is generated"
+		}
+		rel, err := filepath.Rel(m.moduleDir(), edit.Filename)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "file " + f.Filename + " is outside of the main module"
+		}
+	}
+	return ""
+}
+
+// moduleDir returns the directory of the configured main module
+func (m *Modifier) moduleDir() string {
+	if m.modDir == "" {
+		mod, err := config.GetModInfo(m.conf.Module)
+		if err != nil {
+			log.Fatalf("getting main module failed: %+v", err)
+		}
+		m.modDir = mod.Dir
+	}
+	return m.modDir
+}
+
+// isGenerated returns true if the go file has the `// Code generated ... DO NOT EDIT.` comment
+func isGenerated(filename string, src []byte) bool {
+	if !strings.HasSuffix(filename, ".go") {
+		return false
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.PackageClauseOnly|parser.ParseComments)
+	return err == nil && ast.IsGenerated(file)
+}
+
+// verifyFixes reverts the written files of the packages with the new errors after the fixes
+// and of the packages they depend on and returns the reverted files
+func (m *Modifier) verifyFixes(before map[string]*PackageErrors, filenames []string) []string {
+	errs, err := m.typeCheck(filenames)
+	if err != nil {
+		log.Printf("type-checking the fixed packages failed, the fixes are not verified: %+v", err)
+		return nil
+	}
+	var reverted []string
+	for id, after := range errs {
+		// the same error may be reported several times in a file
+		known := make(map[string]int)
+		if pkg, ok := before[id]; ok {
+			for _, e := range pkg.Errors {
+				known[e]++
+			}
+		}
+		broken := false
+		for _, e := range after.Errors {
+			if known[e] == 0 {
+				broken = true
+				break
+			}
+			known[e]--
+		}
+		if !broken {
+			continue
+		}
+		// a package may be broken by the fixes of its dependencies
+		files := after.Files
+		for _, dep := range after.Deps {
+			if pkg, ok := errs[dep]; ok {
+				files = append(files, pkg.Files...)
+			}
+		}
+		for _, filename := range files {
+			if _, ok := m.toFix[filename]; !ok {
+				continue
+			}
+			// the cached file keeps the analyzed source
+			f, err := getFile(filename)
+			if err != nil {
+				log.Fatalf("reading file %q failed: %+v", filename, err)
+			}
+			if err := writeFileAtomic(filename, f.Data); err != nil {
+				log.Fatalf("reverting file %q failed: %+v", filename, err)
+			}
+			delete(m.toFix, filename)
+			reverted = append(reverted, filename)
+		}
+	}
+	return reverted
+}
@@ -0,0 +1,223 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+// cache stores the raw issues of the analyzed packages on disk,
+// so the unchanged packages are not analyzed again
+type cache struct {
+	dir  string
+	salt []byte
+	keys map[*packages.Package]string
+}
+
+func newCache(conf *config.Config) *cache {
+	h := sha256.New()
+	writeVersion(h)
+	// the raw issues depend on the selected analyzers and their flags only, the other options are applied later
+	names := make([]string, 0, len(conf.Analyzers))
+	for name := range conf.Analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name + "\x00"))
+		flags := make([]string, 0, len(conf.Analyzers[name]))
+		for flag, value := range conf.Analyzers[name] {
+			if value != "" {
+				flags = append(flags, flag+"="+value)
+			}
+		}
+		sort.Strings(flags)
+		for _, flag := range flags {
+			h.Write([]byte(flag + "\x00"))
+		}
+		h.Write([]byte{0})
+	}
+	return &cache{
+		dir:  conf.CacheDir,
+		salt: h.Sum(nil),
+		keys: make(map[*packages.Package]string),
+	}
+}
+
+// writeVersion writes the version of the gochecker and all its dependencies,
+// so any update of the analyzers invalidates the cache
+func writeVersion(w io.Writer) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	_, _ = io.WriteString(w, info.GoVersion+"\x00"+info.Main.Path+"@"+info.Main.Version+"\x00")
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			_, _ = io.WriteString(w, setting.Key+"="+setting.Value+"\x00")
+		}
+	}
+	for _, dep := range info.Deps {
+		_, _ = io.WriteString(w, dep.Path+"@"+dep.Version+"\x00")
+		if dep.Replace != nil {
+			_, _ = io.WriteString(w, dep.Replace.Path+"@"+dep.Replace.Version+"\x00")
+		}
+	}
+}
+
+// key returns the hash of the package files and the keys of all its dependencies
+func (c *cache) key(pkg *packages.Package) (string, error) {
+	if k, ok := c.keys[pkg]; ok {
+		return k, nil
+	}
+	h := sha256.New()
+	h.Write(c.salt)
+	h.Write([]byte(pkg.ID + "\x00"))
+	if m := pkg.Module; m != nil && !m.Main && m.Replace == nil && m.Version != "" {
+		// the packages from the module cache are immutable
+		h.Write([]byte(m.Path + "@" + m.Version + "\x00"))
+	} else {
+		for _, filename := range append(append([]string{}, pkg.CompiledGoFiles...), pkg.OtherFiles...) {
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				return "", err
+			}
+			h.Write([]byte(filename + "\x00"))
+			sum := sha256.Sum256(data)
+			h.Write(sum[:])
+		}
+	}
+	paths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		k, err := c.key(pkg.Imports[path])
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(path + "=" + k + "\x00"))
+	}
+	k := hex.EncodeToString(h.Sum(nil))
+	c.keys[pkg] = k
+	return k, nil
+}
+
+func (c *cache) filename(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get returns the stored issues of a package and true if the key is found
+func (c *cache) get(key string) (map[string]*output.IssuesOrError, bool) {
+	data, err := os.ReadFile(c.filename(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("reading cache failed: %+v", err)
+		}
+		return nil, false
+	}
+	var pkg map[string]*output.IssuesOrError
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		log.Printf("decoding cache %q failed: %+v", c.filename(key), err)
+		return nil, false
+	}
+	return pkg, true
+}
+
+// put stores the issues of a package, an empty map is stored as well to mark the package as clean
+func (c *cache) put(key string, pkg map[string]*output.IssuesOrError) {
+	if pkg == nil {
+		pkg = make(map[string]*output.IssuesOrError)
+	}
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		log.Printf("encoding cache failed: %+v", err)
+		return
+	}
+	filename := c.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		log.Printf("creating cache dir failed: %+v", err)
+		return
+	}
+	// write to a temporary file and rename it, so a concurrent run never reads a partial file
+	f, err := os.CreateTemp(filepath.Dir(filename), "tmp-*")
+	if err != nil {
+		log.Printf("creating cache file failed: %+v", err)
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		log.Printf("writing cache file failed: %+v", err)
+		_ = os.Remove(f.Name())
+	}
+}
+
+// lookup loads the list of the packages without parsing the files, emits the stored issues of the unchanged packages
+// and returns the keys of the changed packages by their IDs and the patterns to load them
+func (c *cache) lookup(conf *config.Config, emit emitFunc) (map[string]string, []string, error) {
+	cfg := packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Tests: conf.Test,
+	}
+	pkgs, err := packages.Load(&cfg, conf.Patterns...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		misses   = make(map[string]string)
+		hits     = make(output.Diagnostic)
+		files    = make(map[string][]string)
+		patterns []string
+		seen     = make(map[string]struct{})
+	)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, nil, errors.New("loading packages failed")
+		}
+		if pkg.PkgPath == "command-line-arguments" {
+			return nil, nil, errors.New("packages specified by files are not supported")
+		}
+		key, err := c.key(pkg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if obj, ok := c.get(key); ok {
+			hits[pkg.ID] = obj
+			files[pkg.ID] = pkg.GoFiles
+			continue
+		}
+		misses[pkg.ID] = key
+		// the test variants are loaded by the path of the tested package
+		path := strings.TrimSuffix(strings.TrimSuffix(pkg.PkgPath, ".test"), "_test")
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			patterns = append(patterns, path)
+		}
+	}
+	for pkgName, pkg := range hits {
+		emit(pkgName, files[pkgName], pkg)
+	}
+	return misses, patterns, nil
+}
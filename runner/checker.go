@@ -0,0 +1,374 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/sv-tools/gochecker/analyzers"
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+// action is one unit of the analysis: the application of one analyzer to one package
+type action struct {
+	once         sync.Once
+	analyzer     *analysis.Analyzer
+	pkg          *packages.Package
+	pass         *analysis.Pass
+	deps         []*action
+	objectFacts  map[objectFactKey]analysis.Fact
+	packageFacts map[packageFactKey]analysis.Fact
+	result       any
+	diagnostics  []analysis.Diagnostic
+	err          error
+	duration     time.Duration
+	debug        string
+}
+
+type objectFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+type packageFactKey struct {
+	pkg *types.Package
+	typ reflect.Type
+}
+
+func (act *action) String() string {
+	return act.analyzer.Name + "@" + act.pkg.ID
+}
+
+// selectAnalyzers applies the flags from the config to the analyzers and returns the enabled ones
+func selectAnalyzers(conf *config.Config) []*analysis.Analyzer {
+	if len(conf.Analyzers) == 0 {
+		return analyzers.Analyzers
+	}
+	selected := make([]*analysis.Analyzer, 0, len(conf.Analyzers))
+	for _, analyzer := range analyzers.Analyzers {
+		flags, ok := conf.Analyzers[analyzer.Name]
+		if !ok {
+			continue
+		}
+		for name, value := range flags {
+			if value == "" {
+				continue
+			}
+			if err := analyzer.Flags.Set(name, value); err != nil {
+				log.Fatalf("setting flag %q of analyzer %q failed: %+v", name, analyzer.Name, err)
+			}
+		}
+		selected = append(selected, analyzer)
+	}
+	return selected
+}
+
+// enabledAnalyzers returns the names of the analyzers to be run along with the nolint check if it is enabled
+func enabledAnalyzers(conf *config.Config) []string {
+	var names []string
+	for _, analyzer := range analyzers.Analyzers {
+		if _, ok := conf.Analyzers[analyzer.Name]; ok || len(conf.Analyzers) == 0 {
+			names = append(names, analyzer.Name)
+		}
+	}
+	if conf.Nolint.Check {
+		names = append(names, output.NolintName)
+	}
+	return names
+}
+
+// typeParseError represents a loading error related to typing or parsing only,
+// so the analyzers still can be executed
+type typeParseError struct {
+	error
+}
+
+func loadPackages(conf *config.Config, patterns []string, selected []*analysis.Analyzer) ([]*packages.Package, error) {
+	mode := packages.LoadSyntax
+	if needFacts(selected) {
+		mode = packages.LoadAllSyntax
+	}
+	cfg := packages.Config{
+		Mode:  mode | packages.NeedModule,
+		Tests: conf.Test,
+	}
+	initial, err := packages.Load(&cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if len(initial) == 0 {
+		return nil, fmt.Errorf("%s matched no packages", strings.Join(patterns, " "))
+	}
+
+	n := packages.PrintErrors(initial)
+	if n == 0 {
+		return initial, nil
+	}
+	all := true
+	packages.Visit(initial, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			all = all && (err.Kind == packages.TypeError || err.Kind == packages.ParseError)
+		}
+	})
+	err = fmt.Errorf("%d errors during loading", n)
+	if n == 1 {
+		err = errors.New("error during loading")
+	}
+	if all {
+		return initial, typeParseError{err}
+	}
+	return nil, err
+}
+
+// needFacts reports whether any of the given analyzers or their requirements use facts,
+// so all dependencies must be loaded from source
+func needFacts(selected []*analysis.Analyzer) bool {
+	seen := make(map[*analysis.Analyzer]struct{})
+	queue := append([]*analysis.Analyzer{}, selected...)
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[a]; ok {
+			continue
+		}
+		seen[a] = struct{}{}
+		if len(a.FactTypes) > 0 {
+			return true
+		}
+		queue = append(queue, a.Requires...)
+	}
+	return false
+}
+
+// buildActions creates the graph of actions and returns the root actions grouped by package
+func buildActions(pkgs []*packages.Package, selected []*analysis.Analyzer, debug string) map[*packages.Package][]*action {
+	type key struct {
+		analyzer *analysis.Analyzer
+		pkg      *packages.Package
+	}
+	actions := make(map[key]*action)
+
+	var mkAction func(a *analysis.Analyzer, pkg *packages.Package) *action
+	mkAction = func(a *analysis.Analyzer, pkg *packages.Package) *action {
+		k := key{analyzer: a, pkg: pkg}
+		if act, ok := actions[k]; ok {
+			return act
+		}
+		act := &action{analyzer: a, pkg: pkg, debug: debug}
+		for _, req := range a.Requires {
+			act.deps = append(act.deps, mkAction(req, pkg))
+		}
+		// an analyzer that consumes or produces facts must be run on the dependencies too
+		if len(a.FactTypes) > 0 {
+			paths := make([]string, 0, len(pkg.Imports))
+			for path := range pkg.Imports {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				act.deps = append(act.deps, mkAction(a, pkg.Imports[path]))
+			}
+		}
+		actions[k] = act
+		return act
+	}
+
+	roots := make(map[*packages.Package][]*action, len(pkgs))
+	for _, pkg := range pkgs {
+		for _, a := range selected {
+			roots[pkg] = append(roots[pkg], mkAction(a, pkg))
+		}
+	}
+	return roots
+}
+
+func execAll(actions []*action) {
+	if len(actions) == 0 {
+		return
+	}
+	sequential := strings.Contains(actions[0].debug, "p")
+	wg := sync.WaitGroup{}
+	for _, act := range actions {
+		if sequential {
+			act.exec()
+			continue
+		}
+		wg.Add(1)
+		go func(act *action) {
+			defer wg.Done()
+			act.exec()
+		}(act)
+	}
+	wg.Wait()
+}
+
+func (act *action) exec() {
+	act.once.Do(act.execOnce)
+}
+
+func (act *action) execOnce() {
+	execAll(act.deps)
+
+	start := time.Now()
+	defer func() {
+		act.duration = time.Since(start)
+	}()
+
+	var failed []string
+	for _, dep := range act.deps {
+		if dep.err != nil {
+			failed = append(failed, dep.String())
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		act.err = fmt.Errorf("failed prerequisites: %s", strings.Join(failed, ", "))
+		return
+	}
+
+	inputs := make(map[*analysis.Analyzer]any)
+	act.objectFacts = make(map[objectFactKey]analysis.Fact)
+	act.packageFacts = make(map[packageFactKey]analysis.Fact)
+	for _, dep := range act.deps {
+		if dep.pkg == act.pkg {
+			// same package, the result of a required analyzer
+			inputs[dep.analyzer] = dep.result
+			continue
+		}
+		// same analyzer, the facts from a dependency
+		act.inheritFacts(dep)
+	}
+
+	act.pass = &analysis.Pass{
+		Analyzer:          act.analyzer,
+		Fset:              act.pkg.Fset,
+		Files:             act.pkg.Syntax,
+		OtherFiles:        act.pkg.OtherFiles,
+		IgnoredFiles:      act.pkg.IgnoredFiles,
+		Pkg:               act.pkg.Types,
+		TypesInfo:         act.pkg.TypesInfo,
+		TypesSizes:        act.pkg.TypesSizes,
+		TypeErrors:        act.pkg.TypeErrors,
+		ResultOf:          inputs,
+		Report:            func(d analysis.Diagnostic) { act.diagnostics = append(act.diagnostics, d) },
+		ImportObjectFact:  act.importObjectFact,
+		ExportObjectFact:  act.exportObjectFact,
+		ImportPackageFact: act.importPackageFact,
+		ExportPackageFact: act.exportPackageFact,
+		AllObjectFacts:    act.allObjectFacts,
+		AllPackageFacts:   act.allPackageFacts,
+	}
+
+	if act.pkg.IllTyped && !act.analyzer.RunDespiteErrors {
+		act.err = errors.New("analysis skipped due to errors in package")
+	} else {
+		act.result, act.err = act.analyzer.Run(act.pass)
+		if act.err == nil {
+			if got, want := reflect.TypeOf(act.result), act.analyzer.ResultType; got != want {
+				act.err = fmt.Errorf("internal error: on package %s, analyzer %s returned a result of type %v, but declared ResultType %v", act.pkg.ID, act.analyzer.Name, got, want)
+			}
+		}
+	}
+
+	// disallow calls after Run
+	act.pass.ExportObjectFact = nil
+	act.pass.ExportPackageFact = nil
+}
+
+func (act *action) inheritFacts(dep *action) {
+	for key, fact := range dep.objectFacts {
+		if !exportedFrom(key.obj, dep.pkg.Types) {
+			continue
+		}
+		act.objectFacts[key] = fact
+	}
+	for key, fact := range dep.packageFacts {
+		act.packageFacts[key] = fact
+	}
+}
+
+// exportedFrom reports whether obj may be visible to a package that imports pkg
+func exportedFrom(obj types.Object, pkg *types.Package) bool {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Exported() && obj.Pkg() == pkg || obj.Type().(*types.Signature).Recv() != nil
+	case *types.Var:
+		return obj.IsField() || obj.Pkg() == pkg
+	case *types.TypeName, *types.Const:
+		return true
+	}
+	return false
+}
+
+func factType(fact analysis.Fact) reflect.Type {
+	t := reflect.TypeOf(fact)
+	if t.Kind() != reflect.Ptr {
+		log.Fatalf("invalid Fact type: got %T, want pointer", fact)
+	}
+	return t
+}
+
+func (act *action) importObjectFact(obj types.Object, ptr analysis.Fact) bool {
+	if obj == nil {
+		panic("nil object")
+	}
+	if v, ok := act.objectFacts[objectFactKey{obj: obj, typ: factType(ptr)}]; ok {
+		reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(v).Elem())
+		return true
+	}
+	return false
+}
+
+func (act *action) exportObjectFact(obj types.Object, fact analysis.Fact) {
+	if act.pass.ExportObjectFact == nil {
+		log.Panicf("%s: Pass.ExportObjectFact(%s, %T) called after Run", act, obj, fact)
+	}
+	if obj.Pkg() != act.pkg.Types {
+		log.Panicf("internal error: in analysis %s of package %s: Fact.Set(%s, %T): can't set facts on objects belonging another package", act.analyzer.Name, act.pkg.ID, obj, fact)
+	}
+	act.objectFacts[objectFactKey{obj: obj, typ: factType(fact)}] = fact
+}
+
+func (act *action) allObjectFacts() []analysis.ObjectFact {
+	facts := make([]analysis.ObjectFact, 0, len(act.objectFacts))
+	for k, fact := range act.objectFacts {
+		facts = append(facts, analysis.ObjectFact{Object: k.obj, Fact: fact})
+	}
+	return facts
+}
+
+func (act *action) importPackageFact(pkg *types.Package, ptr analysis.Fact) bool {
+	if pkg == nil {
+		panic("nil package")
+	}
+	if v, ok := act.packageFacts[packageFactKey{pkg: pkg, typ: factType(ptr)}]; ok {
+		reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(v).Elem())
+		return true
+	}
+	return false
+}
+
+func (act *action) exportPackageFact(fact analysis.Fact) {
+	if act.pass.ExportPackageFact == nil {
+		log.Panicf("%s: Pass.ExportPackageFact(%T) called after Run", act, fact)
+	}
+	act.packageFacts[packageFactKey{pkg: act.pass.Pkg, typ: factType(fact)}] = fact
+}
+
+func (act *action) allPackageFacts() []analysis.PackageFact {
+	facts := make([]analysis.PackageFact, 0, len(act.packageFacts))
+	for k, fact := range act.packageFacts {
+		facts = append(facts, analysis.PackageFact{Package: k.pkg, Fact: fact})
+	}
+	return facts
+}
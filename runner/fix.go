@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/sv-tools/gochecker/config"
+	"github.com/sv-tools/gochecker/output"
+)
+
+// linePosRE matches the line and the column at the end of the position of an error
+var linePosRE = regexp.MustCompile(`(:\d+){1,2}$`)
+
+// Fix runs the analyzers and applies the suggested fixes, with `-interactive` flag each fix is confirmed by the user
+func Fix() {
+	conf := config.ParseConfig()
+	conf.Fix = true
+	run(conf)
+}
+
+// fixConflicts runs the analyzers, which fixes were dropped because of the conflicts with other fixes,
+// on the modified files again and applies the updated fixes until there are no conflicts
+// or the number of the iterations is exceeded, the issues left unfixed and the remaining conflicts are reported
+func fixConflicts(conf *config.Config, m *output.Modifier, ordered bool, report func(diag *output.Diagnostic)) {
+	// the fixes of the patch are not applied, so the conflicts cannot be fixed
+	for i := 0; !conf.DryRun && conf.Patch == "" && i < conf.FixIterations && len(m.Conflicts()) > 0; i++ {
+		retry := *conf
+		retry.Analyzers = make(map[string]map[string]string)
+		retry.Patterns = nil
+		retry.NoCache = true
+		// only the already accepted fixes are applied, so the baseline is not needed
+		retry.Baseline = ""
+		retry.Interactive = false
+		retry.Nolint.Check = false
+		files := make(map[string]struct{})
+		for _, pkg := range m.Conflicts() {
+			for analyzerName, obj := range pkg {
+				if analyzerName == output.NolintName {
+					// the directives are checked after all analyzers only
+					continue
+				}
+				retry.Analyzers[analyzerName] = conf.Analyzers[analyzerName]
+				for _, issue := range obj.Issues {
+					for _, fix := range issue.SuggestedFixes {
+						for _, edit := range fix.Edits {
+							files[edit.Filename] = struct{}{}
+						}
+					}
+				}
+			}
+		}
+		if len(retry.Analyzers) == 0 {
+			break
+		}
+		for filename := range files {
+			retry.Patterns = append(retry.Patterns, "file="+filepath.Clean(filename))
+		}
+		if strings.Contains(conf.Debug, "v") {
+			log.Printf("fix iteration %d: %d analyzers, %d files", i+1, len(retry.Analyzers), len(files))
+		}
+
+		// the files have been changed by the fixes
+		output.ResetFileCache()
+		r := m.Retry(&retry)
+		analyze(&retry, ordered, func(pkgName string, files []string, pkg map[string]*output.IssuesOrError) {
+			if r.Package(pkgName, files, pkg) {
+				report(&output.Diagnostic{pkgName: pkg})
+			}
+		})
+		if diag := r.Finish(); len(diag) > 0 {
+			report(&diag)
+		}
+		m = r
+	}
+	diag := make(output.Diagnostic)
+	m.ReportConflicts(diag)
+	if len(diag) > 0 {
+		report(&diag)
+	}
+}
+
+// typeCheck returns the function to load the packages of the given files and their reverse dependencies
+// in the main module from the source and to collect their loading and typing errors
+func typeCheck(conf *config.Config) output.TypeChecker {
+	return func(filenames []string) (map[string]*output.PackageErrors, error) {
+		mod, err := config.GetModInfo(conf.Module)
+		if err != nil {
+			return nil, err
+		}
+		cfg := packages.Config{
+			Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports,
+			Dir:   mod.Dir,
+			Tests: conf.Test,
+		}
+		listed, err := packages.Load(&cfg, "./...")
+		if err != nil {
+			return nil, err
+		}
+		fixed := make(map[string]struct{}, len(filenames))
+		for _, filename := range filenames {
+			fixed[filepath.Clean(filename)] = struct{}{}
+		}
+		byID := make(map[string]*packages.Package, len(listed))
+		importers := make(map[string][]*packages.Package)
+		var queue []*packages.Package
+		for _, pkg := range listed {
+			byID[pkg.ID] = pkg
+			for _, imp := range pkg.Imports {
+				importers[imp.ID] = append(importers[imp.ID], pkg)
+			}
+			if slices.ContainsFunc(pkg.GoFiles, func(filename string) bool {
+				_, ok := fixed[filename]
+				return ok
+			}) {
+				queue = append(queue, pkg)
+			}
+		}
+		checked := make(map[string]struct{})
+		patterns := make(map[string]struct{})
+		for len(queue) > 0 {
+			pkg := queue[0]
+			queue = queue[1:]
+			if _, ok := checked[pkg.ID]; ok {
+				continue
+			}
+			checked[pkg.ID] = struct{}{}
+			queue = append(queue, importers[pkg.ID]...)
+			// the test binaries are generated, the external test packages are loaded with their packages
+			if !strings.HasSuffix(pkg.ID, ".test") {
+				patterns[strings.TrimSuffix(pkg.PkgPath, "_test")] = struct{}{}
+			}
+		}
+		if len(patterns) == 0 {
+			return nil, nil
+		}
+		paths := make([]string, 0, len(patterns))
+		for path := range patterns {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		cfg.Mode = packages.LoadSyntax
+		pkgs, err := packages.Load(&cfg, paths...)
+		if err != nil {
+			return nil, err
+		}
+		errs := make(map[string]*output.PackageErrors, len(pkgs))
+		for _, pkg := range pkgs {
+			if _, ok := checked[pkg.ID]; !ok {
+				continue
+			}
+			pe := output.PackageErrors{Files: pkg.GoFiles, Deps: checkedDeps(byID, checked, pkg.ID)}
+			for _, e := range pkg.Errors {
+				// the fixes shift the positions, so the errors are compared by the files and the messages
+				pe.Errors = append(pe.Errors, linePosRE.ReplaceAllString(e.Pos, "")+": "+e.Msg)
+			}
+			errs[pkg.ID] = &pe
+		}
+		return errs, nil
+	}
+}
+
+// checkedDeps returns the IDs of the checked packages, which the package depends on directly or indirectly
+func checkedDeps(byID map[string]*packages.Package, checked map[string]struct{}, id string) []string {
+	var deps []string
+	seen := map[string]struct{}{id: {}}
+	queue := []string{id}
+	for len(queue) > 0 {
+		pkg, ok := byID[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, imp := range pkg.Imports {
+			if _, ok := seen[imp.ID]; ok {
+				continue
+			}
+			seen[imp.ID] = struct{}{}
+			// the dependencies outside of the checked packages cannot depend on them
+			if _, ok := checked[imp.ID]; ok {
+				deps = append(deps, imp.ID)
+				queue = append(queue, imp.ID)
+			}
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
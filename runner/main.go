@@ -11,8 +11,8 @@ import (
 )
 
 const (
-	Prog             = "gochecker"
-	InterceptModeEnv = "330311d0-6a15-420d-8a66-a35b3e0f9c40"
+	Prog                = "gochecker"
+	DefaultBaselineFile = ".gochecker-baseline.json"
 )
 
 func Main() {
@@ -20,16 +20,11 @@ func Main() {
 	log.SetFlags(0)
 	log.SetPrefix(Prog + ": ")
 
-	if os.Getenv(InterceptModeEnv) != "" {
-		// pass directly to multichecker
-		multichecker.Main(analyzers.Analyzers...)
-	}
-
 	// check for any sub-commands
 	commands()
 
-	// intercept the output of the multichecker and do the job
-	Intercept()
+	// run the analyzers and do the job
+	Run()
 }
 
 func commands() {
@@ -41,5 +36,13 @@ func commands() {
 		multichecker.Main(analyzers.Analyzers...)
 	case "generate-config":
 		config.GenerateConfig()
+	case "baseline":
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		Baseline()
+		os.Exit(0)
+	case "fix":
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		Fix()
+		os.Exit(0)
 	}
 }
@@ -1,81 +1,477 @@
 package runner
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
 
 	"github.com/sv-tools/gochecker/config"
 	"github.com/sv-tools/gochecker/output"
 )
 
-func Intercept() {
-	conf := config.ParseConfig()
+// Run loads the packages, executes the analyzers in-process and prints the issues of each package
+// as soon as the package is analyzed
+func Run() {
+	run(config.ParseConfig())
+}
+
+func run(conf *config.Config) {
+	var (
+		m        = output.NewModifier(conf)
+		printers = newPrinters(conf, m)
+		all      = make(output.Diagnostic)
+		collect  = false
+		ordered  = true
+		failed   = false
+	)
+	if conf.Fix {
+		m.SetTypeChecker(typeCheck(conf))
+	}
+	for _, p := range printers {
+		if p.stream {
+			// the streamed packages are printed as soon as they are analyzed
+			ordered = false
+		} else {
+			collect = true
+		}
+	}
+	printDiag := func(diag *output.Diagnostic) {
+		for _, p := range printers {
+			if p.stream && p.print(p.w, diag) {
+				failed = true
+			}
+		}
+		if !collect {
+			return
+		}
+		// a document cannot be streamed, so collect all packages
+		for pkgName, pkg := range *diag {
+			if _, ok := all[pkgName]; !ok {
+				all[pkgName] = pkg
+				continue
+			}
+			// the issues of the nolint check and the skipped fixes are added to the already collected package
+			for analyzerName, obj := range pkg {
+				if collected, ok := all[pkgName][analyzerName]; ok && collected.Error == "" {
+					collected.Issues = append(collected.Issues, obj.Issues...)
+					continue
+				}
+				all[pkgName][analyzerName] = obj
+			}
+		}
+	}
+
+	analyze(conf, ordered, func(pkgName string, files []string, pkg map[string]*output.IssuesOrError) {
+		if m.Package(pkgName, files, pkg) {
+			printDiag(&output.Diagnostic{pkgName: pkg})
+		}
+	})
+	if diag := m.Finish(); len(diag) > 0 {
+		printDiag(&diag)
+	}
+	if conf.Fix {
+		// the issues left after the retries are printed and counted along with other issues
+		fixConflicts(conf, m, ordered, printDiag)
+		// the pending fixes of the patch fail the run like the issues
+		if m.Summary().Patched > 0 {
+			failed = true
+		}
+	}
 
-	buf := runMultiChecker(conf.Args...)
-	// exit if no issues
-	if len(buf) == 3 && bytes.Equal(bytes.TrimSpace(buf), []byte("{}")) {
-		return
+	for _, p := range printers {
+		// keep stdout empty if there are no issues to be printed as json
+		if !p.stream && (len(all) > 0 || p.conf.Format != config.JSONOutput || !p.conf.IsStdout() || conf.Summary) && p.print(p.w, &all) {
+			failed = true
+		}
+		if p.summary != nil {
+			p.summary(p.w, m.Summary())
+		}
+		if p.w != os.Stdout && p.w != os.Stderr {
+			if err := p.w.Close(); err != nil {
+				log.Printf("closing output file %q failed: %+v", p.conf.Path, err)
+			}
+		}
 	}
-	diag := output.ParseOutput(conf, buf)
-	if len(*diag) == 0 {
-		os.Exit(0)
+	if failed {
+		os.Exit(3)
 	}
-	switch conf.Output {
-	case config.ConsoleOutput:
-		if output.PrintAsConsole(diag) {
-			os.Exit(3)
+}
+
+// printer writes the issues in one of the output formats either package by package or all at once at the end
+type printer struct {
+	conf    *config.OutputFile
+	w       *os.File
+	stream  bool
+	print   func(w io.Writer, diag *output.Diagnostic) bool
+	summary func(w io.Writer, s *output.Summary)
+}
+
+func newPrinters(conf *config.Config, m *output.Modifier) []*printer {
+	// the issues grouped by files or analyzers are known at the end only
+	stream := conf.Group == "" || conf.Group == config.PackageKey
+	printers := make([]*printer, 0, len(conf.Outputs))
+	for _, o := range conf.Outputs {
+		p := printer{conf: o, w: os.Stdout}
+		if conf.DryRun {
+			// stdout holds the patch only
+			p.w = os.Stderr
+		}
+		switch o.Format {
+		case config.ConsoleOutput:
+			p.stream = stream
+			p.print = func(w io.Writer, diag *output.Diagnostic) bool {
+				return output.PrintAsConsole(w, conf, diag)
+			}
+			if conf.Summary {
+				p.summary = output.PrintSummary
+			}
+		case config.GithubOutput:
+			p.stream = stream
+			p.print = func(w io.Writer, diag *output.Diagnostic) bool {
+				return output.PrintAsGithub(w, conf, diag)
+			}
+			if conf.Summary {
+				p.summary = output.PrintSummaryAsGithub
+			}
+		case config.JSONLinesOutput:
+			p.stream = true
+			p.print = noFailure(output.PrintAsJSONLines)
+		case config.JSONOutput:
+			p.print = noFailure(output.PrintAsJSON)
+			if conf.Summary {
+				p.print = noFailure(func(w io.Writer, diag *output.Diagnostic) {
+					output.PrintAsJSONWithSummary(w, diag, m.Summary())
+				})
+			}
+		case config.SARIFOutput:
+			p.print = noFailure(output.PrintAsSARIF)
+		case config.CheckstyleOutput:
+			p.print = noFailure(output.PrintAsCheckstyle)
+		case config.JUnitOutput:
+			p.print = noFailure(func(w io.Writer, diag *output.Diagnostic) {
+				output.PrintAsJUnit(w, diag, m.Packages(), enabledAnalyzers(conf))
+			})
+		case config.GitlabOutput:
+			p.print = noFailure(output.PrintAsGitlab)
 		}
-	case config.JSONOutput:
-		e := json.NewEncoder(os.Stdout)
-		e.SetIndent("", "  ")
-		if err := e.Encode(diag); err != nil {
-			log.Fatalf("json ouput failed: %+v", err)
+		if !o.IsStdout() {
+			f, err := os.Create(o.Path)
+			if err != nil {
+				log.Fatalf("creating output file %q failed: %+v", o.Path, err)
+			}
+			p.w = f
 		}
-	case config.GithubOutput:
-		if output.PrintAsGithub(diag) {
-			os.Exit(3)
+		printers = append(printers, &p)
+	}
+	return printers
+}
+
+// noFailure wraps the print function of the machine-readable format, which never fails the run
+func noFailure(fn func(w io.Writer, diag *output.Diagnostic)) func(w io.Writer, diag *output.Diagnostic) bool {
+	return func(w io.Writer, diag *output.Diagnostic) bool {
+		fn(w, diag)
+		return false
+	}
+}
+
+// emitFunc receives the issues and the go files of an analyzed package
+type emitFunc func(pkgName string, files []string, pkg map[string]*output.IssuesOrError)
+
+// orderedEmitter emits the packages sorted by their IDs, so the output does not depend on the order of analysis.
+// The packages are held until the list of all packages is known and all preceding packages are emitted,
+// so a slow package holds all packages after it and the emitter is used only if no output is streamed.
+type orderedEmitter struct {
+	emit    emitFunc
+	started bool
+	ids     []string
+	ready   map[string]func()
+}
+
+func newOrderedEmitter(emit emitFunc) *orderedEmitter {
+	return &orderedEmitter{emit: emit, ready: make(map[string]func())}
+}
+
+func (o *orderedEmitter) add(pkgName string, files []string, pkg map[string]*output.IssuesOrError) {
+	o.ready[pkgName] = func() { o.emit(pkgName, files, pkg) }
+	o.next()
+}
+
+// start sets the packages to be analyzed, the already added packages are emitted in order with them
+func (o *orderedEmitter) start(ids []string) {
+	for id := range o.ready {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	o.ids = ids
+	o.started = true
+	o.next()
+}
+
+func (o *orderedEmitter) next() {
+	for o.started && len(o.ids) > 0 {
+		fn, ok := o.ready[o.ids[0]]
+		if !ok {
+			return
 		}
+		delete(o.ready, o.ids[0])
+		o.ids = o.ids[1:]
+		fn()
+	}
+}
+
+// flush emits the remaining packages, which may be held by a package never analyzed
+func (o *orderedEmitter) flush() {
+	ids := make([]string, 0, len(o.ready))
+	for id := range o.ready {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		o.ready[id]()
 	}
+	o.ids = nil
+	o.ready = make(map[string]func())
 }
 
-func runMultiChecker(args ...string) []byte {
+// analyze runs the analyzers and calls the emit function for each analyzed package from a single goroutine
+// either in the order of the package IDs or as soon as the package is analyzed,
+// the packages without issues are emitted as well
+func analyze(conf *config.Config, ordered bool, emit emitFunc) {
+	defer profile(conf)()
+
+	var emitter *orderedEmitter
+	if ordered {
+		emitter = newOrderedEmitter(emit)
+		defer emitter.flush()
+		emit = emitter.add
+	}
+
+	selected := selectAnalyzers(conf)
 	var (
-		stderr bytes.Buffer
-		stdout bytes.Buffer
+		c      *cache
+		misses map[string]string
 	)
-	prog, err := os.Executable()
-	if err != nil {
-		log.Fatalf("getting execuable failed: %+v", err)
+	patterns := conf.Patterns
+	if !conf.NoCache {
+		c = newCache(conf)
+		var err error
+		misses, patterns, err = c.lookup(conf, emit)
+		switch {
+		case err != nil:
+			log.Printf("using cache failed, analyzing all packages: %+v", err)
+			c = nil
+			patterns = conf.Patterns
+		case len(misses) == 0:
+			if emitter != nil {
+				emitter.start(nil)
+			}
+			return
+		}
+	}
+
+	if strings.Contains(conf.Debug, "v") {
+		log.Printf("load %s", patterns)
 	}
-	prog, err = filepath.EvalSymlinks(prog)
+	pkgs, err := loadPackages(conf, patterns, selected)
 	if err != nil {
-		log.Fatalf("evaluating symlink failed: %+v", err)
+		var typeErr typeParseError
+		if !errors.As(err, &typeErr) {
+			log.Print(err)
+			os.Exit(1)
+		}
 	}
 
-	cmd := exec.Command(prog, args...)
-	cmd.Env = append(os.Environ(), InterceptModeEnv+"=on")
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err = cmd.Run()
-	code := -1
-	if cmd.ProcessState != nil {
-		code = cmd.ProcessState.ExitCode()
+	if c != nil {
+		// the patterns of changed packages load the unchanged test variants too
+		tmp := pkgs[:0]
+		for _, pkg := range pkgs {
+			if _, ok := misses[pkg.ID]; ok {
+				tmp = append(tmp, pkg)
+			}
+		}
+		pkgs = tmp
 	}
-	if stderr.Len() > 0 {
-		if _, err = stderr.WriteTo(os.Stderr); err != nil {
-			log.Printf("writing to stderr failed: %+v", err)
-			if code == 0 {
-				code = 1
+	if emitter != nil {
+		ids := make([]string, 0, len(pkgs))
+		for _, pkg := range pkgs {
+			ids = append(ids, pkg.ID)
+		}
+		emitter.start(ids)
+	}
+
+	roots := buildActions(pkgs, selected, conf.Debug)
+	done := make(chan *packages.Package)
+	go func() {
+		defer close(done)
+		if strings.Contains(conf.Debug, "p") {
+			for _, pkg := range pkgs {
+				execAll(roots[pkg])
+				done <- pkg
 			}
+			return
 		}
-		os.Exit(code)
+		wg := sync.WaitGroup{}
+		for _, pkg := range pkgs {
+			wg.Add(1)
+			go func(pkg *packages.Package) {
+				defer wg.Done()
+				execAll(roots[pkg])
+				done <- pkg
+			}(pkg)
+		}
+		wg.Wait()
+	}()
+
+	for pkg := range done {
+		diag := make(output.Diagnostic)
+		failed := pkg.IllTyped
+		for _, act := range roots[pkg] {
+			diag.Add(pkg.Fset, pkg.ID, act.analyzer.Name, act.diagnostics, act.err)
+			// the diagnostics are not needed anymore, so release the memory
+			act.diagnostics = nil
+			failed = failed || act.err != nil
+		}
+		// store the issues before any modification, the failed packages are always analyzed again
+		if c != nil && !failed {
+			c.put(misses[pkg.ID], diag[pkg.ID])
+		}
+		emit(pkg.ID, pkg.GoFiles, diag[pkg.ID])
 	}
-	if err != nil {
-		log.Fatalf("interception failed: %+v", err)
+
+	if strings.Contains(conf.Debug, "t") {
+		all := make([]*action, 0, len(roots)*len(selected))
+		for _, actions := range roots {
+			all = append(all, actions...)
+		}
+		printTimings(all)
+	}
+}
+
+// Baseline runs the analyzers and records all reported issues into the baseline file
+func Baseline() {
+	conf := config.ParseConfig()
+	filename := conf.Baseline
+	if filename == "" {
+		filename = DefaultBaselineFile
+	}
+	// the baseline must contain all current issues, including the ones merged into the issues of other analyzers
+	conf.Baseline = ""
+	conf.Fix = false
+	conf.Merge = false
+
+	m := output.NewModifier(conf)
+	b := output.NewBaseline()
+	add := func(pkgName string, pkg map[string]*output.IssuesOrError) {
+		for analyzerName, obj := range pkg {
+			for _, issue := range obj.Issues {
+				b.Add(pkgName, analyzerName, issue)
+			}
+		}
+	}
+	// the duplicates are recorded for the same variants of the packages in every run
+	analyze(conf, true, func(pkgName string, files []string, pkg map[string]*output.IssuesOrError) {
+		if m.Package(pkgName, files, pkg) {
+			add(pkgName, pkg)
+		}
+	})
+	for pkgName, pkg := range m.Finish() {
+		add(pkgName, pkg)
+	}
+
+	if err := b.Save(filename); err != nil {
+		log.Fatalf("writing baseline file %q failed: %+v", filename, err)
+	}
+	log.Printf("%d issues recorded to the baseline file %q", len(b.Issues), filename)
+}
+
+// profile starts the cpu profiling and the tracing if requested and returns a function to stop them
+// and to write the memory profile
+func profile(conf *config.Config) func() {
+	var stops []func()
+	if conf.CPUProfile != "" {
+		f, err := os.Create(conf.CPUProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal(err)
+		}
+		stops = append(stops, pprof.StopCPUProfile)
+	}
+	if conf.Trace != "" {
+		f, err := os.Create(conf.Trace)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := trace.Start(f); err != nil {
+			log.Fatal(err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			log.Printf("To view the trace, run:\n$ go tool trace view %s", conf.Trace)
+		})
+	}
+	if conf.MemProfile != "" {
+		f, err := os.Create(conf.MemProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stops = append(stops, func() {
+			runtime.GC() // get up-to-date statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				log.Fatalf("writing memory profile failed: %+v", err)
+			}
+			if err := f.Close(); err != nil {
+				log.Fatalf("closing memory profile failed: %+v", err)
+			}
+		})
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
+
+func printTimings(actions []*action) {
+	seen := make(map[*action]struct{})
+	var visit func(actions []*action)
+	visit = func(actions []*action) {
+		for _, act := range actions {
+			if _, ok := seen[act]; !ok {
+				seen[act] = struct{}{}
+				visit(act.deps)
+			}
+		}
+	}
+	visit(actions)
+
+	all := make([]*action, 0, len(seen))
+	var total time.Duration
+	for act := range seen {
+		all = append(all, act)
+		total += act.duration
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].duration > all[j].duration
+	})
+	// print actions accounting for 90% of the total
+	var sum time.Duration
+	for _, act := range all {
+		log.Printf("%s\t%s", act.duration, act)
+		sum += act.duration
+		if sum >= total*9/10 {
+			break
+		}
 	}
-	return stdout.Bytes()
 }